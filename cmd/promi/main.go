@@ -26,17 +26,19 @@ func main() {
 		l.Fatal(err)
 	}
 
-	app := kong.Parse(&cli, king.DefaultOptions(
+	opts := king.DefaultOptions(
 		king.Config{
 			Name:        "promi",
 			Description: "CLI to query targets and alerts of multiple prometheus servers.",
 			BuildInfo:   b,
 		},
-	)...)
+	)
+
+	app := kong.Parse(&cli, opts...)
 
 	l.SetDebug(cli.Debug)
 
-	if err := app.Run(&cli.Globals, l.Get()); err != nil {
+	if err := app.Run(&cli.Globals, l.Get(), cmd.ParserOptions(opts)); err != nil {
 		l.Fatal(err)
 	}
 }
@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"regexp"
+	"time"
 
-	"github.com/alecthomas/kong"
 	"github.com/postfinance/promi/internal/prometheus"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/zbindenren/sfmt"
@@ -13,32 +14,69 @@ import (
 )
 
 type alertCmd struct {
-	Output      string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
-	NoHeaders   bool   `short:"n" help:"Do not display headers in table output."`
-	alertFilter `prefix:"filter-"`
+	Output        string                    `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders     bool                      `short:"n" help:"Do not display headers in table output."`
+	Deduplicate   bool                      `help:"Deduplicate alerts with identical labels of multiple prometheus servers."`
+	ReplicaLabels []string                  `name:"replica-label" help:"Labels to ignore on deduplication (e.g. the replica label of HA prometheus servers)."`
+	Annotations   []string                  `name:"annotation" help:"Annotations to show as additional columns in table output (e.g. summary)."`
+	SortBy        []prometheus.AlertSortKey `enum:"since,name,server,severity" help:"Sort alerts by keys (since|name|server|severity). Alerts with equal keys are sorted by server and name."`
+	Where         string                    `short:"w" help:"Filter alerts by expression (e.g. 'state=firing and not (severity=\"info\" or server=~\"lab.*\")'). Regular expressions are fully anchored, unlike the --filter-* flags."`
+	alertFilter   `prefix:"filter-"`
+
+	List     alertListCmd     `cmd:"" default:"1" help:"Show alerts (default)."`
+	Describe alertDescribeCmd `cmd:"" help:"Show all instances of an alert with labels, annotations, value and active time."`
 }
 
-func (a alertCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) error {
-	c, err := g.client()
+// alerts returns the filtered and optionally deduplicated alerts.
+func (a alertCmd) alerts(g *Globals, l *zap.SugaredLogger) (prometheus.Alerts, error) {
+	c, err := g.client(l)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
 	defer cancel()
 
-	alerts, err := c.Alerts(ctx)
+	alerts, warnings, err := c.Alerts(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	logWarnings(l, warnings)
+
 	filters, err := a.alertFilter.filters()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if a.Where != "" {
+		e, err := prometheus.ParseExpr(a.Where)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, e.AlertFilter())
 	}
 
 	alerts = alerts.Filter(filters...)
 
+	if a.Deduplicate {
+		alerts = alerts.Deduplicate(a.ReplicaLabels...)
+	}
+
+	alerts.Sort(a.SortBy...)
+
+	return alerts, nil
+}
+
+type alertListCmd struct{}
+
+func (alertListCmd) Run(a *alertCmd, g *Globals, l *zap.SugaredLogger) error {
+	alerts, err := a.alerts(g, l)
+	if err != nil {
+		return err
+	}
+
 	s := sfmt.SliceWriter{
 		Writer:    os.Stdout,
 		NoHeaders: a.NoHeaders,
@@ -46,16 +84,43 @@ func (a alertCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) error
 
 	format := sfmt.ParseFormat(a.Output)
 
-	s.Write(format, alerts)
+	if format == sfmt.Table && len(a.Annotations) > 0 {
+		return s.Write(format, alerts.WithAnnotations(a.Annotations...))
+	}
 
-	return nil
+	return s.Write(format, alerts)
+}
+
+type alertDescribeCmd struct {
+	Name string `arg:"" help:"The name of the alert."`
+}
+
+func (d alertDescribeCmd) Run(a *alertCmd, g *Globals, l *zap.SugaredLogger) error {
+	alerts, err := a.alerts(g, l)
+	if err != nil {
+		return err
+	}
+
+	alerts = alerts.Filter(prometheus.AlertByName(regexp.MustCompile("^" + regexp.QuoteMeta(d.Name) + "$")))
+	if len(alerts) == 0 {
+		return fmt.Errorf("no alert %q found", d.Name)
+	}
+
+	format := sfmt.ParseFormat(a.Output)
+	if format != sfmt.Table {
+		return sfmt.SliceWriter{Writer: os.Stdout}.Write(format, alerts)
+	}
+
+	return alerts.Describe(os.Stdout)
 }
 
 type alertFilter struct {
-	Name   string        `short:"N" help:"Filter alerts by job name (regular expression)."`
-	Alert  string        `short:"a" help:"Filter alerts by alert name (regular expression)."`
-	Server string        `short:"S" help:"Filter alerts by prometheus server name (regular expression)."`
-	State  v1.AlertState `short:"s" help:"Filter alerts by state (pending|firing)" enum:"pending,firing,"`
+	Name      string        `short:"N" help:"Filter alerts by job name (regular expression)."`
+	Alert     string        `short:"a" help:"Filter alerts by alert name (regular expression)."`
+	Server    string        `short:"S" help:"Filter alerts by prometheus server name (regular expression)."`
+	State     v1.AlertState `short:"s" help:"Filter alerts by state (pending|firing)" enum:"pending,firing,"`
+	OlderThan time.Duration `help:"Filter alerts that are active for more than the duration (e.g. 1h)."`
+	NewerThan time.Duration `help:"Filter alerts that became active within the duration (e.g. 10m)."`
 }
 
 func (a alertFilter) filters() ([]prometheus.AlertFilterFunc, error) {
@@ -92,5 +157,13 @@ func (a alertFilter) filters() ([]prometheus.AlertFilterFunc, error) {
 		filters = append(filters, prometheus.AlertByState(a.State))
 	}
 
+	if a.OlderThan > 0 {
+		filters = append(filters, prometheus.AlertByOlderThan(a.OlderThan))
+	}
+
+	if a.NewerThan > 0 {
+		filters = append(filters, prometheus.AlertByNewerThan(a.NewerThan))
+	}
+
 	return filters, nil
 }
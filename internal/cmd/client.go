@@ -2,29 +2,166 @@
 package cmd
 
 import (
+	"context"
+	"os"
 	"time"
 
+	"github.com/alecthomas/kong"
+	"github.com/postfinance/promi/internal/discovery"
 	"github.com/postfinance/promi/internal/prometheus"
 	"github.com/zbindenren/king"
+	"go.uber.org/zap"
 )
 
 // CLI is the client command.
 type CLI struct {
 	Globals
-	Alerts  alertCmd  `cmd:"" help:"Show alerts." aliases:"a"`
-	Targets targetCmd `cmd:"" help:"Show targets." aliases:"t"`
-	Server  serverCmd `cmd:"" help:"Start a web server running the Prometheus React UI."`
+	Alerts   alertCmd    `cmd:"" help:"Show alerts." aliases:"a"`
+	Targets  targetCmd   `cmd:"" help:"Show targets." aliases:"t"`
+	Rules    ruleCmd     `cmd:"" help:"Show alerting and recording rules." aliases:"r"`
+	Query    queryCmd    `cmd:"" help:"Evaluate a PromQL query on all prometheus servers." aliases:"q"`
+	Labels   labelsCmd   `cmd:"" help:"Show the label names or the values of a label with the prometheus servers they are present on."`
+	Series   seriesCmd   `cmd:"" help:"Show the number of series per metric and prometheus server."`
+	Metadata metadataCmd `cmd:"" help:"Show type, unit and help of metrics per prometheus server."`
+	TSDB     tsdbCmd     `cmd:"" name:"tsdb" help:"Show the top cardinality statistics of the TSDB of all prometheus servers."`
+	Config   configCmd   `cmd:"" help:"Compare the configurations of the prometheus servers."`
+	Servers  serversCmd  `cmd:"" help:"Show reachability, version and runtime information of the prometheus servers."`
+	Server   serverCmd   `cmd:"" help:"Start a web server running the Prometheus React UI."`
+	TUI      tuiCmd      `cmd:"" name:"tui" help:"Start an interactive terminal UI for targets, alerts and rules."`
+}
+
+// ParserOptions are the options of the command line parser. They are used to
+// parse the configuration again on reload.
+type ParserOptions []kong.Option
+
+// parse parses the command line arguments, environment variables and configuration
+// files into a new CLI.
+func (o ParserOptions) parse() (*CLI, error) {
+	cli := CLI{}
+
+	parser, err := kong.New(&cli, o...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parser.Parse(os.Args[1:]); err != nil {
+		return nil, err
+	}
+
+	return &cli, nil
 }
 
 // Globals are the global client flags.
 type Globals struct {
-	PrometheusURLs []string         `short:"u" name:"prometheus-urls" help:"A comma separated list of prometheus base URLs." default:"http://localhost:9090"`
+	PrometheusURLs []string         `short:"u" name:"prometheus-urls" help:"A comma separated list of prometheus base URLs (default http://localhost:9090 without service discovery)."`
 	ShowConfig     king.ShowConfig  `help:"Show used config files"`
 	Version        king.VersionFlag `help:"Show version information"`
 	Debug          bool             `short:"d" help:"Show debug output." `
 	Timeout        time.Duration    `help:"The http request timeout." default:"20s"`
+	ServerTimeout  time.Duration    `help:"The http request timeout for a single prometheus server (0 means no limit)." default:"0s"`
+	MaxInFlight    int              `help:"The maximum number of concurrent requests to all prometheus servers (0 means no limit)." default:"0"`
+	Retries        int              `help:"The number of retries of requests on transient errors (connection refused, 502, 503, 504)." default:"2"`
+	RetryBackoff   time.Duration    `help:"The initial backoff between retries. It doubles on every retry." default:"100ms"`
+	Breaker        breakerFlags     `embed:"" prefix:"breaker-"`
+	Discovery      discoveryFlags   `embed:"" prefix:"discovery-"`
+	Cached         bool             `help:"Show the cached targets and alerts of the server instead of requesting the prometheus servers."`
+	CacheFile      string           `type:"path" help:"The file of the cache of the last successful results (written by the server)." default:"~/.cache/promi/cache.json"`
+}
+
+type breakerFlags struct {
+	Threshold int           `default:"5" help:"The number of consecutive failures after which requests to a prometheus server are skipped (0 disables the circuit breaker)."`
+	Cooldown  time.Duration `default:"30s" help:"The duration requests to a failing prometheus server are skipped."`
 }
 
-func (g Globals) client() (*prometheus.Client, error) {
-	return prometheus.New(g.PrometheusURLs...)
+type discoveryFlags struct {
+	DNS      []string      `name:"dns" help:"DNS SRV records (_prometheus._tcp.example.com) or host names with port (prometheus.example.com:9090) to discover prometheus servers."`
+	File     []string      `help:"Files in file_sd format (json|yaml) to discover prometheus servers. The files are watched for changes."`
+	HTTP     []string      `name:"http" help:"URLs returning prometheus servers in http_sd format."`
+	Scheme   string        `default:"http" enum:"http,https" help:"The scheme of discovered prometheus servers without __scheme__ label."`
+	Interval time.Duration `default:"1m" help:"The interval to refresh the discovered prometheus servers."`
+}
+
+func (d discoveryFlags) enabled() bool {
+	return len(d.DNS)+len(d.File)+len(d.HTTP) > 0
+}
+
+// defaultPrometheusURL is used if neither prometheus URLs nor service discovery are configured.
+const defaultPrometheusURL = "http://localhost:9090"
+
+// urls returns the static prometheus URLs.
+func (g Globals) urls() []string {
+	if len(g.PrometheusURLs) == 0 && !g.Discovery.enabled() {
+		return []string{defaultPrometheusURL}
+	}
+
+	return g.PrometheusURLs
+}
+
+func (g Globals) client(l *zap.SugaredLogger, opts ...prometheus.Option) (*prometheus.Client, error) {
+	urls := g.urls()
+
+	if g.Cached {
+		cache, err := prometheus.LoadCache(g.CacheFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return prometheus.New(urls, prometheus.WithCacheOnly(cache))
+	}
+
+	if m := g.discovery(l); m != nil {
+		var err error
+
+		urls, err = m.Discover(context.Background())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return prometheus.New(urls, append([]prometheus.Option{
+		prometheus.WithServerTimeout(g.ServerTimeout),
+		prometheus.WithMaxInFlight(g.MaxInFlight),
+		prometheus.WithRetries(g.Retries, g.RetryBackoff),
+		prometheus.WithCircuitBreaker(g.Breaker.Threshold, g.Breaker.Cooldown),
+	}, opts...)...)
+}
+
+// runDiscovery refreshes the prometheus servers of the client in the background, if
+// service discovery is configured. The returned function stops the discovery.
+func (g Globals) runDiscovery(l *zap.SugaredLogger, c *prometheus.Client) func() {
+	m := g.discovery(l)
+	if m == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go m.Run(ctx, c.URLs(), func(urls []string) error {
+		return c.SetURLs(urls...)
+	})
+
+	return cancel
+}
+
+// discovery returns the discovery manager or nil if no service discovery is configured.
+func (g Globals) discovery(l *zap.SugaredLogger) *discovery.Manager {
+	if !g.Discovery.enabled() {
+		return nil
+	}
+
+	discoverers := []discovery.Discoverer{}
+
+	for _, name := range g.Discovery.DNS {
+		discoverers = append(discoverers, discovery.NewDNS(name, g.Discovery.Scheme))
+	}
+
+	for _, path := range g.Discovery.File {
+		discoverers = append(discoverers, discovery.NewFile(path, g.Discovery.Scheme))
+	}
+
+	for _, u := range g.Discovery.HTTP {
+		discoverers = append(discoverers, discovery.NewHTTP(u, g.Discovery.Scheme))
+	}
+
+	return discovery.NewManager(l, g.urls(), g.Discovery.Interval, g.Timeout, discoverers...)
 }
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGlobalsClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+- targets:
+    - prometheus101.example.com:9090
+`), 0600))
+
+	t.Run("without prometheus urls and discovery", func(t *testing.T) {
+		c, err := Globals{}.client(zap.NewNop().Sugar())
+		require.NoError(t, err)
+		assert.Equal(t, []string{defaultPrometheusURL}, c.URLs())
+	})
+
+	t.Run("discovery without prometheus urls", func(t *testing.T) {
+		g := Globals{Discovery: discoveryFlags{File: []string{path}, Scheme: "http"}}
+
+		c, err := g.client(zap.NewNop().Sugar())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://prometheus101.example.com:9090"}, c.URLs(), "the default url must not be used with discovery")
+	})
+
+	t.Run("discovery with prometheus urls", func(t *testing.T) {
+		g := Globals{
+			PrometheusURLs: []string{"http://prometheus100.example.com:9090"},
+			Discovery:      discoveryFlags{File: []string{path}, Scheme: "http"},
+		}
+
+		c, err := g.client(zap.NewNop().Sugar())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://prometheus100.example.com:9090", "http://prometheus101.example.com:9090"}, c.URLs())
+	})
+}
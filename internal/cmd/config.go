@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type configCmd struct {
+	Diff configDiffCmd `cmd:"" help:"Show the differences of the configurations of the prometheus servers."`
+}
+
+type configDiffCmd struct {
+	Server               string   `short:"S" help:"Select prometheus servers by name (regular expression)."`
+	IgnoreExternalLabels bool     `help:"Ignore all external labels."`
+	IgnoreLabels         []string `name:"ignore-label" help:"External labels to ignore (e.g. the replica label of HA prometheus servers)."`
+	Groups               bool     `short:"g" help:"Show groups of servers with identical configuration instead of the differences."`
+	Output               string   `short:"o" default:"table" enum:"json,yaml,table" help:"Output format of the groups (table|json|yaml)."`
+	NoHeaders            bool     `short:"n" help:"Do not display headers in table output."`
+}
+
+func (d configDiffCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	// the servers are selected before the configurations are requested, so that
+	// unreachable servers that are not selected do not fail the diff
+	if d.Server != "" {
+		if err := selectServers(c, d.Server); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	configs, err := c.Configs(ctx)
+	if err != nil {
+		return err
+	}
+
+	configs, err = configs.Normalize(d.IgnoreExternalLabels, d.IgnoreLabels...)
+	if err != nil {
+		return err
+	}
+
+	if d.Groups {
+		s := sfmt.SliceWriter{
+			Writer:    os.Stdout,
+			NoHeaders: d.NoHeaders,
+		}
+
+		return s.Write(sfmt.ParseFormat(d.Output), configs.Groups())
+	}
+
+	return configs.Diff(os.Stdout)
+}
+
+// selectServers replaces the prometheus servers of the client with the servers whose
+// name matches the regular expression.
+func selectServers(c *prometheus.Client, expr string) error {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+
+	urls := []string{}
+
+	for _, server := range c.ServerNames() {
+		if r.MatchString(server) {
+			urls = append(urls, c.ServerURL(server))
+		}
+	}
+
+	if len(urls) == 0 {
+		return fmt.Errorf("no prometheus server selected")
+	}
+
+	return c.SetURLs(urls...)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectServers(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"success","data":{"yaml":"global:\n  scrape_interval: 15s\n"}}`)
+	}))
+	defer s.Close()
+
+	// the unselected server is unreachable
+	c, err := prometheus.New([]string{s.URL, "http://localhost:1"}, prometheus.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, selectServers(c, "^127"))
+	assert.Equal(t, []string{s.URL}, c.URLs())
+
+	configs, err := c.Configs(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, configs, 1)
+
+	assert.Error(t, selectServers(c, "prometheus101"), "no server selected")
+}
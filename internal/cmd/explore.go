@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+// timeRange are the flags of the time range of label and series requests.
+type timeRange struct {
+	Start string `help:"The start time (RFC3339 or unix timestamp, default the beginning of the retention)."`
+	End   string `help:"The end time (RFC3339 or unix timestamp, default now)."`
+}
+
+func (t timeRange) parse() (time.Time, time.Time, error) {
+	start, err := parseTime(t.Start, time.Unix(0, 0))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %w", err)
+	}
+
+	end, err := parseTime(t.End, time.Now())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %w", err)
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("end must not be before start")
+	}
+
+	return start, end, nil
+}
+
+type labelsCmd struct {
+	Output    string   `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders bool     `short:"n" help:"Do not display headers in table output."`
+	Match     []string `short:"m" help:"Series selectors of the series to get the labels from."`
+	MissingOn bool     `short:"M" help:"Show only label names or values missing on some servers."`
+	timeRange
+	Name string `arg:"" optional:"" help:"Show the values of the label instead of the label names."`
+}
+
+func (lc labelsCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := lc.parse()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	presences, warnings, err := c.LabelPresences(ctx, lc.Name, lc.Match, start, end)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	if lc.MissingOn {
+		presences = presences.Missing()
+	}
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: lc.NoHeaders,
+	}
+
+	return w.Write(sfmt.ParseFormat(lc.Output), presences)
+}
+
+type seriesCmd struct {
+	Output    string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders bool   `short:"n" help:"Do not display headers in table output."`
+	List      bool   `short:"l" xor:"list" help:"List the series with their server instead of the number of series per metric and server."`
+	MissingOn bool   `short:"M" xor:"list" help:"Show only metrics missing on some servers (not with --list)."`
+	timeRange
+	Matchers []string `arg:"" help:"Series selectors (e.g. up or 'up{job=\"node\"}')."`
+}
+
+func (s seriesCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := s.parse()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: s.NoHeaders,
+	}
+
+	if s.List {
+		series, warnings, err := c.Series(ctx, s.Matchers, start, end)
+		if err != nil {
+			return err
+		}
+
+		logWarnings(l, warnings)
+
+		return w.Write(sfmt.ParseFormat(s.Output), serverSeries(series))
+	}
+
+	counts, warnings, err := c.SeriesCounts(ctx, s.Matchers, start, end)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	if s.MissingOn {
+		return w.Write(sfmt.ParseFormat(s.Output), counts.Presences().Missing())
+	}
+
+	return w.Write(sfmt.ParseFormat(s.Output), counts)
+}
+
+// series is a series of a prometheus server.
+type series struct {
+	Server string         `json:"server"`
+	Labels model.LabelSet `json:"labels"`
+}
+
+// Header represents the header of a series row.
+func (s series) Header() []string {
+	return []string{"SERVER", "SERIES"}
+}
+
+// Row represents a series row.
+func (s series) Row() []string {
+	return []string{s.Server, s.Labels.String()}
+}
+
+// serverSeries returns the series sorted by server and labels with the server label
+// moved to the server column.
+func serverSeries(labelSets []model.LabelSet) []series {
+	result := make([]series, 0, len(labelSets))
+
+	for _, ls := range labelSets {
+		server := string(ls[prometheus.SourceLabelName])
+		delete(ls, prometheus.SourceLabelName)
+
+		result = append(result, series{Server: server, Labels: ls})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Server != result[j].Server {
+			return result[i].Server < result[j].Server
+		}
+
+		return result[i].Labels.Before(result[j].Labels)
+	})
+
+	return result
+}
+
+// logWarnings logs the warnings of requests to the prometheus servers.
+func logWarnings(l *zap.SugaredLogger, warnings v1.Warnings) {
+	for _, w := range warnings {
+		l.Warn(w)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type metadataCmd struct {
+	Output    string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders bool   `short:"n" help:"Do not display headers in table output."`
+	Conflicts bool   `short:"c" help:"Show only metrics whose type, unit or help differ between servers or targets."`
+	Metric    string `arg:"" optional:"" help:"The metric name (default all metrics)."`
+}
+
+func (m metadataCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	metadata, warnings, err := c.Metadata(ctx, m.Metric)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	if m.Conflicts {
+		metadata = metadata.Conflicts()
+	}
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: m.NoHeaders,
+	}
+
+	return w.Write(sfmt.ParseFormat(m.Output), metadata)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/postfinance/promi/internal/global"
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type queryCmd struct {
+	Output        string        `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders     bool          `short:"n" help:"Do not display headers in table output."`
+	Global        bool          `short:"g" help:"Evaluate the query with promi on the raw samples of all prometheus servers instead of on every server."`
+	Time          string        `help:"The evaluation time of an instant query (RFC3339 or unix timestamp, default now)."`
+	Start         string        `help:"The start time of a range query (RFC3339 or unix timestamp)."`
+	End           string        `help:"The end time of a range query (RFC3339 or unix timestamp, default now)."`
+	Step          time.Duration `default:"1m" help:"The resolution step of a range query."`
+	Deduplicate   bool          `help:"Deduplicate the series of HA replicas."`
+	ReplicaLabels []string      `name:"replica-label" help:"Labels to ignore on deduplication (e.g. the replica label of HA prometheus servers)."`
+	Query         string        `arg:"" help:"The PromQL expression."`
+}
+
+// querier evaluates instant and range queries.
+type querier interface {
+	Query(ctx context.Context, query string, ts time.Time) (model.Value, v1.Warnings, error)
+	QueryRange(ctx context.Context, query string, r v1.Range) (model.Value, v1.Warnings, error)
+}
+
+func (q queryCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	var e querier = c
+
+	if q.Global {
+		e = global.New(c, global.WithTimeout(g.Timeout), global.WithDeduplicate(q.Deduplicate, q.ReplicaLabels...))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	v, warnings, err := q.eval(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	// the series of the global engine are deduplicated before the evaluation
+	if q.Deduplicate && !q.Global {
+		v = prometheus.DeduplicateValue(v, q.ReplicaLabels...)
+	}
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: q.NoHeaders,
+	}
+
+	return w.Write(sfmt.ParseFormat(q.Output), samples(v))
+}
+
+// eval evaluates a range query if a start time is set and an instant query otherwise.
+func (q queryCmd) eval(ctx context.Context, e querier) (model.Value, v1.Warnings, error) {
+	if q.Start == "" {
+		ts, err := parseTime(q.Time, time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid time: %w", err)
+		}
+
+		return e.Query(ctx, q.Query, ts)
+	}
+
+	start, err := parseTime(q.Start, time.Time{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid start: %w", err)
+	}
+
+	end, err := parseTime(q.End, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid end: %w", err)
+	}
+
+	if end.Before(start) {
+		return nil, nil, errors.New("end must not be before start")
+	}
+
+	if q.Step <= 0 {
+		return nil, nil, errors.New("step must be positive")
+	}
+
+	return e.QueryRange(ctx, q.Query, v1.Range{Start: start, End: end, Step: q.Step})
+}
+
+// parseTime parses a RFC3339 or unix timestamp. An empty string returns def.
+func parseTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	return prometheus.ParseTime(s)
+}
+
+// sample is a row of a query result.
+type sample struct {
+	Metric    model.Metric `json:"metric"`
+	Timestamp time.Time    `json:"timestamp"`
+	Value     string       `json:"value"`
+}
+
+// Header represents the header of a query result row.
+func (s sample) Header() []string {
+	return []string{"METRIC", "TIMESTAMP", "VALUE"}
+}
+
+// Row represents a query result row.
+func (s sample) Row() []string {
+	metric := ""
+	if s.Metric != nil {
+		metric = s.Metric.String()
+	}
+
+	return []string{metric, s.Timestamp.Format(time.RFC3339), s.Value}
+}
+
+// samples returns a row for every sample of a query result.
+func samples(v model.Value) []sample {
+	result := []sample{}
+
+	switch v := v.(type) {
+	case model.Vector:
+		for _, s := range v {
+			result = append(result, sample{Metric: s.Metric, Timestamp: s.Timestamp.Time(), Value: s.Value.String()})
+		}
+	case model.Matrix:
+		for _, s := range v {
+			for _, p := range s.Values {
+				result = append(result, sample{Metric: s.Metric, Timestamp: p.Timestamp.Time(), Value: p.Value.String()})
+			}
+		}
+	case *model.Scalar:
+		result = append(result, sample{Timestamp: v.Timestamp.Time(), Value: v.Value.String()})
+	case *model.String:
+		result = append(result, sample{Timestamp: v.Timestamp.Time(), Value: v.Value})
+	}
+
+	return result
+}
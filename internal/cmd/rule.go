@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"regexp"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type ruleCmd struct {
+	Output     string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders  bool   `short:"n" help:"Do not display headers in table output."`
+	ruleFilter `prefix:"filter-"`
+
+	List ruleListCmd `cmd:"" default:"1" help:"Show rules (default)."`
+	Diff ruleDiffCmd `cmd:"" help:"Show rule groups and rules that are missing on some servers or whose query, for duration or labels differ."`
+}
+
+// rules returns the filtered rules.
+func (r ruleCmd) rules(ctx context.Context, c *prometheus.Client) (prometheus.Rules, error) {
+	rules, err := c.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := r.ruleFilter.filters()
+	if err != nil {
+		return nil, err
+	}
+
+	rules = rules.Filter(filters...)
+	rules.Sort()
+
+	return rules, nil
+}
+
+type ruleListCmd struct{}
+
+func (ruleListCmd) Run(r *ruleCmd, g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	rules, err := r.rules(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	s := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: r.NoHeaders,
+	}
+
+	return s.Write(sfmt.ParseFormat(r.Output), rules)
+}
+
+type ruleDiffCmd struct{}
+
+func (ruleDiffCmd) Run(r *ruleCmd, g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	rules, err := c.Rules(ctx)
+	if err != nil {
+		return err
+	}
+
+	servers, err := r.ruleFilter.servers(c.ServerNames())
+	if err != nil {
+		return err
+	}
+
+	filters, err := r.ruleFilter.filters()
+	if err != nil {
+		return err
+	}
+
+	// the rules of the selected servers are compared unfiltered, the other filters
+	// select the differences, otherwise filtered rules are reported as missing
+	selected := map[string]bool{}
+	for _, server := range servers {
+		selected[server] = true
+	}
+
+	rules = rules.Filter(func(rule prometheus.Rule) bool { return selected[rule.Server()] })
+
+	s := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: r.NoHeaders,
+	}
+
+	return s.Write(sfmt.ParseFormat(r.Output), rules.Diff(servers, filters...))
+}
+
+type ruleFilter struct {
+	Name     string `short:"N" help:"Filter rules by name (regular expression)."`
+	Group    string `short:"G" help:"Filter rules by group name (regular expression)."`
+	Server   string `short:"S" help:"Filter rules by prometheus server name (regular expression)."`
+	Selector string `short:"s" help:"Filter rules by (k8s style) selector."`
+}
+
+func (r ruleFilter) filters() ([]prometheus.RuleFilterFunc, error) {
+	filters := []prometheus.RuleFilterFunc{}
+
+	if r.Name != "" {
+		re, err := regexp.Compile(r.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, prometheus.RuleByName(re))
+	}
+
+	if r.Group != "" {
+		re, err := regexp.Compile(r.Group)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, prometheus.RuleByGroup(re))
+	}
+
+	if r.Server != "" {
+		re, err := regexp.Compile(r.Server)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, prometheus.RuleByServer(re))
+	}
+
+	if r.Selector != "" {
+		sel, err := labels.Parse(r.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, prometheus.RuleBySelector(sel))
+	}
+
+	return filters, nil
+}
+
+// servers returns the names of the servers selected by the server filter.
+func (r ruleFilter) servers(names []string) ([]string, error) {
+	if r.Server == "" {
+		return names, nil
+	}
+
+	re, err := regexp.Compile(r.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := []string{}
+
+	for _, n := range names {
+		if re.MatchString(n) {
+			servers = append(servers, n)
+		}
+	}
+
+	return servers, nil
+}
@@ -1,34 +1,171 @@
 package cmd
 
 import (
-	"errors"
+	"context"
+	"os"
+	"os/signal"
 	"regexp"
+	"syscall"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/postfinance/promi/internal/notifier"
+	"github.com/postfinance/promi/internal/prometheus"
 	"github.com/postfinance/promi/internal/web"
 	"github.com/zbindenren/king"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout is the maximum duration to finish the open requests on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 type serverCmd struct {
-	ListerAddr  string `default:":8080" help:"The TCP address for the server to listen on"`
-	Deduplicate bool   `help:"Deduplicate targets by scrape url."`
+	ListerAddr      string   `default:":8080" help:"The TCP address for the server to listen on"`
+	Deduplicate     bool     `help:"Deduplicate targets by scrape url, alerts by labels and query results of HA replicas."`
+	ReplicaLabels   []string `name:"replica-label" help:"Labels to ignore on alert and query deduplication (e.g. the replica label of HA prometheus servers)."`
+	targetDedup     `prefix:"dedup-"`
+	EventsInterval  time.Duration `default:"30s" help:"The interval to compare targets and alerts for the events stream."`
+	NotifierConfig  string        `type:"existingfile" help:"Path to the configuration file for target health notifications."`
+	StaleGrace      time.Duration `default:"5m" help:"The duration the last successful results of an unreachable prometheus server are served (0 disables the cache)."`
+	EnableLifecycle bool          `help:"Enable the reload of the configuration with a POST or PUT request to /-/reload."`
 }
 
-func (s serverCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) error {
+func (s serverCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context, opts ParserOptions) error {
 	l.Infow("starting http server",
 		king.FlagMap(app, regexp.MustCompile("key"), regexp.MustCompile("password"), regexp.MustCompile("secret")).
 			Rm("help", "env-help", "version", "show-config", "etcd-ca", "etcd-cert").
 			List()...)
 
-	if len(g.PrometheusURLs) == 0 {
-		return errors.New("no prometheus server configured")
+	// the cache is kept on reload
+	cache := s.cache(g)
+
+	c, err := g.client(l, prometheus.WithCache(cache))
+	if err != nil {
+		return err
+	}
+
+	stopDiscovery := g.runDiscovery(l, c)
+
+	var a *web.API
+
+	// reload re-reads the configuration and swaps the prometheus client and the
+	// server options. It is serialized by web.API.Reload.
+	reload := func() error {
+		cli, err := opts.parse()
+		if err != nil {
+			return err
+		}
+
+		c, err := cli.Globals.client(l, prometheus.WithCache(cache))
+		if err != nil {
+			return err
+		}
+
+		if err := a.Apply(c, cli.Server.options(&cli.Globals)...); err != nil {
+			return err
+		}
+
+		s.warnRestart(l, cli.Server)
+
+		stopDiscovery()
+		stopDiscovery = cli.Globals.runDiscovery(l, c)
+
+		return nil
 	}
 
-	a, err := web.New(l, g.Timeout, s.Deduplicate, g.PrometheusURLs...)
+	a, err = web.New(l, c, append(s.options(g),
+		web.WithEventsInterval(s.EventsInterval),
+		web.WithReloader(reload),
+	)...)
 	if err != nil {
 		return err
 	}
 
-	return a.Start()
+	if s.NotifierConfig != "" {
+		cfg, err := notifier.LoadConfig(s.NotifierConfig)
+		if err != nil {
+			return err
+		}
+
+		n := notifier.New(l, cfg, func(ctx context.Context) (prometheus.Targets, error) {
+			ctx, cancel := context.WithTimeout(ctx, g.Timeout)
+			defer cancel()
+
+			// failing servers are notified by the health of their scraper targets
+			targets, _, err := a.Client().Targets(ctx, true)
+
+			return targets, err
+		})
+
+		go n.Run(context.Background())
+	}
+
+	go reloadOnSignal(a)
+	go shutdownOnSignal(l, a)
+
+	if err := a.Start(); err != nil {
+		return err
+	}
+
+	// the results of the last cache save interval are lost otherwise
+	return cache.Save()
+}
+
+// cache returns the cache of the last successful results or nil if it is disabled.
+func (s serverCmd) cache(g *Globals) *prometheus.Cache {
+	if s.StaleGrace <= 0 {
+		return nil
+	}
+
+	return prometheus.NewCache(g.CacheFile, s.StaleGrace)
+}
+
+// options returns the web options that can be changed on reload.
+func (s serverCmd) options(g *Globals) []web.Option {
+	return []web.Option{
+		web.WithTimeout(g.Timeout),
+		web.WithDeduplicate(s.Deduplicate, s.ReplicaLabels...),
+		web.WithTargetDedupOptions(s.targetDedup.options()...),
+		web.WithLifecycle(s.EnableLifecycle),
+	}
+}
+
+// warnRestart logs the changed options that are not applied on reload.
+func (s serverCmd) warnRestart(l *zap.SugaredLogger, reloaded serverCmd) {
+	if reloaded.EventsInterval != s.EventsInterval {
+		l.Warnw("events interval is not changed on reload, a restart is required", "interval", s.EventsInterval)
+	}
+
+	if reloaded.NotifierConfig != s.NotifierConfig {
+		l.Warnw("notifier config is not changed on reload, a restart is required", "config", s.NotifierConfig)
+	}
+
+	if reloaded.StaleGrace != s.StaleGrace {
+		l.Warnw("stale grace is not changed on reload, a restart is required", "grace", s.StaleGrace)
+	}
+}
+
+// shutdownOnSignal gracefully stops the server on SIGINT and SIGTERM.
+func shutdownOnSignal(l *zap.SugaredLogger, a *web.API) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	<-sig
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := a.Shutdown(ctx); err != nil {
+		l.Errorw("failed to shutdown server", "err", err)
+	}
+}
+
+// reloadOnSignal reloads the configuration on SIGHUP.
+func reloadOnSignal(a *web.API) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		_ = a.Reload() // errors are logged and reported in the metrics
+	}
 }
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type serversCmd struct {
+	Output    string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders bool   `short:"n" help:"Do not display headers in table output."`
+}
+
+func (s serversCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: s.NoHeaders,
+	}
+
+	return w.Write(sfmt.ParseFormat(s.Output), c.Servers(ctx))
+}
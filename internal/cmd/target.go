@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"regexp"
+	"time"
 
-	"github.com/alecthomas/kong"
 	"github.com/postfinance/promi/internal/prometheus"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/zbindenren/sfmt"
@@ -14,14 +15,51 @@ import (
 )
 
 type targetCmd struct {
-	Output       string `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
-	Compact      bool   `short:"c" help:"Do not display labels and last error."`
-	NoHeaders    bool   `short:"n" help:"Do not display headers in table output."`
+	Output       string   `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	Compact      bool     `short:"c" help:"Do not display labels and last error."`
+	NoHeaders    bool     `short:"n" help:"Do not display headers in table output."`
+	Deduplicate  bool     `help:"Deduplicate targets of multiple prometheus servers by scrape url."`
+	SortBy       []string `help:"Sort targets by keys (health|last-scrape|duration|server|job|scrape-url|samples|series-added|label:<name>). Targets with equal keys are sorted by job, server and scrape url."`
+	Reverse      bool     `help:"Reverse the sort order."`
+	WithStats    bool     `help:"Show the samples scraped, series added and scrape duration of the last scrape (one query per prometheus server)."`
+	Where        string   `short:"w" help:"Filter targets by expression (e.g. 'health=down and (job=~\"node.*\" or env=\"prod\")'). Regular expressions are fully anchored, unlike the --filter-* flags."`
+	targetDedup  `prefix:"dedup-"`
 	targetFilter `prefix:"filter-"`
+
+	List     targetListCmd     `cmd:"" default:"1" help:"Show targets (default)."`
+	Metadata targetMetadataCmd `cmd:"" help:"Show the metrics exposed by the targets with type, unit and help."`
 }
 
-func (t targetCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) error {
-	c, err := g.client()
+// targets returns the filtered targets. The errors of single servers are logged as warnings.
+func (t targetCmd) targets(ctx context.Context, l *zap.SugaredLogger, c *prometheus.Client) (prometheus.Targets, error) {
+	targets, warnings, err := c.Targets(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	logWarnings(l, warnings)
+
+	filters, err := t.targetFilter.filters()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Where != "" {
+		e, err := prometheus.ParseExpr(t.Where)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, e.TargetFilter())
+	}
+
+	return targets.Filter(filters...), nil
+}
+
+type targetListCmd struct{}
+
+func (targetListCmd) Run(t *targetCmd, g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
 	if err != nil {
 		return err
 	}
@@ -29,18 +67,36 @@ func (t targetCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) erro
 	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
 	defer cancel()
 
-	targets, err := c.Targets(ctx, false)
+	targets, err := t.targets(ctx, l, c)
 	if err != nil {
 		return err
 	}
 
-	filters, err := t.targetFilter.filters()
+	keys, err := sortKeys(t.SortBy)
 	if err != nil {
 		return err
 	}
 
-	targets = targets.Filter(filters...)
-	targets.Sort()
+	if t.WithStats {
+		warnings, err := c.TargetStats(ctx, targets)
+		if err != nil {
+			return err
+		}
+
+		logWarnings(l, warnings)
+	} else {
+		for _, k := range keys {
+			if k == prometheus.TargetSortBySamples || k == prometheus.TargetSortBySeriesAdded {
+				return fmt.Errorf("sort key %s requires --with-stats", k)
+			}
+		}
+	}
+
+	if t.Deduplicate {
+		targets = targets.Deduplicate(t.targetDedup.options()...)
+	}
+
+	targets.SortBy(t.Reverse, keys...)
 
 	if t.Compact {
 		targets.Compact()
@@ -58,12 +114,76 @@ func (t targetCmd) Run(g *Globals, l *zap.SugaredLogger, app *kong.Context) erro
 	return nil
 }
 
+type targetMetadataCmd struct {
+	Metric string `short:"m" help:"Show only the metadata of the metric."`
+}
+
+func (m targetMetadataCmd) Run(t *targetCmd, g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	targets, err := t.targets(ctx, l, c)
+	if err != nil {
+		return err
+	}
+
+	metadata, warnings, err := c.TargetsMetadata(ctx, targets, m.Metric)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	s := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: t.NoHeaders,
+	}
+
+	return s.Write(sfmt.ParseFormat(t.Output), metadata)
+}
+
+// sortKeys parses the target sort keys.
+func sortKeys(s []string) ([]prometheus.TargetSortKey, error) {
+	keys := make([]prometheus.TargetSortKey, 0, len(s))
+
+	for _, v := range s {
+		k, err := prometheus.ParseTargetSortKey(v)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+type targetDedup struct {
+	Policy prometheus.DedupPolicy `default:"worst-health" enum:"worst-health,best-health,majority,most-recent-scrape" help:"Policy to select the health of deduplicated targets (worst-health|best-health|majority|most-recent-scrape)."`
+	Labels []string               `name:"label" help:"Labels to identify identical targets in addition to the scrape url."`
+}
+
+func (t targetDedup) options() []prometheus.DedupOption {
+	return []prometheus.DedupOption{
+		prometheus.WithDedupPolicy(t.Policy),
+		prometheus.WithDedupLabels(t.Labels...),
+	}
+}
+
 type targetFilter struct {
-	Name      string          `short:"N" help:"Filter targets by job name (regular expression)."`
-	Server    string          `short:"S" help:"Filter targets by promehteus server name (regular expression)."`
-	ScrapeURL string          `short:"e" help:"Filter targets by scrape endpoint url (regular expression)."`
-	Health    v1.HealthStatus `short:"H" help:"Filter targets by health (up|down)" enum:"up,down,unknown,"`
-	Selector  string          `short:"s" help:"Filter services by (k8s style) selector."`
+	Name       string          `short:"N" help:"Filter targets by job name (regular expression)."`
+	Server     string          `short:"S" help:"Filter targets by promehteus server name (regular expression)."`
+	ScrapeURL  string          `short:"e" help:"Filter targets by scrape endpoint url (regular expression)."`
+	Health     v1.HealthStatus `short:"H" help:"Filter targets by health (up|down)" enum:"up,down,unknown,"`
+	Selector   string          `short:"s" help:"Filter services by (k8s style) selector."`
+	StaleFor   time.Duration   `help:"Filter targets whose last scrape is older than the duration (e.g. 2m)."`
+	SlowerThan time.Duration   `help:"Filter targets whose last scrape took longer than the duration (e.g. 5s)."`
+	LastError  string          `help:"Filter targets by the error of the last scrape (regular expression)."`
 }
 
 func (t targetFilter) filters() ([]prometheus.TargetFilterFunc, error) {
@@ -109,5 +229,22 @@ func (t targetFilter) filters() ([]prometheus.TargetFilterFunc, error) {
 		filters = append(filters, prometheus.TargetBySelector(sel))
 	}
 
+	if t.StaleFor > 0 {
+		filters = append(filters, prometheus.TargetByStaleFor(t.StaleFor))
+	}
+
+	if t.SlowerThan > 0 {
+		filters = append(filters, prometheus.TargetBySlowerThan(t.SlowerThan))
+	}
+
+	if t.LastError != "" {
+		r, err := regexp.Compile(t.LastError)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, prometheus.TargetByLastError(r))
+	}
+
 	return filters, nil
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/zbindenren/sfmt"
+	"go.uber.org/zap"
+)
+
+type tsdbCmd struct {
+	Output    string                    `short:"o" default:"table" enum:"json,yaml,table" help:"Output format (table|json|yaml)."`
+	NoHeaders bool                      `short:"n" help:"Do not display headers in table output."`
+	Top       int                       `short:"t" default:"10" help:"The number of the top statistics of every kind."`
+	Kinds     []prometheus.TSDBStatKind `name:"kind" short:"k" enum:"head,series,label-values,memory,label-value-pairs" default:"head,series,label-values,memory,label-value-pairs" help:"The kinds of statistics (head|series|label-values|memory|label-value-pairs)."`
+	Save      string                    `type:"path" help:"Save a snapshot of the statistics to the file."`
+	Compare   string                    `type:"existingfile" help:"Show the growth against a snapshot saved with --save."`
+}
+
+func (t tsdbCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	// the snapshot to compare is loaded before saving, because it can be the same file
+	var previous *prometheus.TSDBSnapshot
+
+	if t.Compare != "" {
+		previous, err = prometheus.LoadTSDBSnapshot(t.Compare)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+
+	snapshot, warnings, err := c.TSDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	logWarnings(l, warnings)
+
+	if t.Save != "" {
+		if err := snapshot.Save(t.Save); err != nil {
+			return err
+		}
+	}
+
+	stats := snapshot.Stats().ByKind(t.Kinds...)
+
+	if previous != nil {
+		stats.Compare(previous.Stats())
+	}
+
+	w := sfmt.SliceWriter{
+		Writer:    os.Stdout,
+		NoHeaders: t.NoHeaders,
+	}
+
+	return w.Write(sfmt.ParseFormat(t.Output), stats.Top(t.Top))
+}
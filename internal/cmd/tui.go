@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/postfinance/promi/internal/tui"
+	"go.uber.org/zap"
+)
+
+type tuiCmd struct {
+	Refresh time.Duration `default:"10s" help:"The interval to refresh the targets, alerts and rules."`
+}
+
+func (t tuiCmd) Run(g *Globals, l *zap.SugaredLogger) error {
+	c, err := g.client(l)
+	if err != nil {
+		return err
+	}
+
+	return tui.New(c, t.Refresh, g.Timeout).Run()
+}
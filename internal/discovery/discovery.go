@@ -0,0 +1,189 @@
+// Package discovery discovers prometheus servers.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	schemeLabelName = "__scheme__"
+	watchInterval   = 5 * time.Second
+)
+
+// Discoverer discovers the URLs of prometheus servers.
+type Discoverer interface {
+	// Discover returns the URLs of the discovered servers.
+	Discover(ctx context.Context) ([]string, error)
+	// String returns the name of the discoverer for logging.
+	String() string
+}
+
+// watcher is implemented by discoverers that can detect changes without a discovery.
+type watcher interface {
+	Changed() bool
+}
+
+// Manager periodically discovers prometheus servers of all discoverers.
+type Manager struct {
+	l           *zap.SugaredLogger
+	static      []string
+	discoverers []Discoverer
+	interval    time.Duration
+	timeout     time.Duration
+
+	mu      sync.Mutex
+	results map[Discoverer][]string
+}
+
+// NewManager creates a new Manager. The static URLs are always part of the
+// discovered URLs.
+func NewManager(l *zap.SugaredLogger, static []string, interval, timeout time.Duration, discoverers ...Discoverer) *Manager {
+	return &Manager{
+		l:           l,
+		static:      static,
+		discoverers: discoverers,
+		interval:    interval,
+		timeout:     timeout,
+		results:     map[Discoverer][]string{},
+	}
+}
+
+// Discover returns the static and discovered URLs. If a discoverer fails, its last
+// successful result is used. An error is only returned, if a discoverer has never
+// been successful.
+func (m *Manager) Discover(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var discoverErr error
+
+	for _, d := range m.discoverers {
+		urls, err := d.Discover(ctx)
+		if err != nil {
+			if _, ok := m.results[d]; !ok {
+				discoverErr = fmt.Errorf("%s: %w", d, err)
+			}
+
+			m.l.Warnw("service discovery failed", "discoverer", d.String(), "err", err)
+
+			continue
+		}
+
+		m.results[d] = urls
+	}
+
+	urls := append([]string{}, m.static...)
+	for _, d := range m.discoverers {
+		urls = append(urls, m.results[d]...)
+	}
+
+	return unique(urls), discoverErr
+}
+
+// Run calls update with the discovered URLs in the configured interval or if a
+// watched source (e.g. a file) has changed until the context is canceled. Update
+// is only called if the URLs have changed.
+func (m *Manager) Run(ctx context.Context, current []string, update func([]string) error) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	watch := time.NewTicker(watchInterval)
+	defer watch.Stop()
+
+	current = unique(current)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-watch.C:
+			if !m.changed() {
+				continue
+			}
+		}
+
+		urls, err := m.Discover(ctx)
+		if err != nil {
+			m.l.Errorw("failed to discover prometheus servers", "err", err)
+		}
+
+		if equal(current, urls) {
+			continue
+		}
+
+		if err := update(urls); err != nil {
+			m.l.Errorw("failed to update prometheus servers", "err", err)
+			continue
+		}
+
+		m.l.Infow("updated prometheus servers", "urls", urls)
+
+		current = urls
+	}
+}
+
+func (m *Manager) changed() bool {
+	for _, d := range m.discoverers {
+		if w, ok := d.(watcher); ok && w.Changed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serverURL returns the URL of host and port with the scheme.
+func serverURL(scheme, host string, port int) string {
+	u := url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+	}
+
+	return u.String()
+}
+
+func unique(urls []string) []string {
+	m := map[string]bool{}
+	result := []string{}
+
+	for _, u := range urls {
+		if m[u] {
+			continue
+		}
+
+		m[u] = true
+
+		result = append(result, u)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "promi")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "servers.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+- targets:
+    - prometheus101.example.com:9090
+    - prometheus102.example.com:9090
+- targets:
+    - prometheus103.example.com:9090
+  labels:
+    __scheme__: https
+`), 0600))
+
+	f := NewFile(path, "http")
+
+	urls, err := f.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://prometheus101.example.com:9090",
+		"http://prometheus102.example.com:9090",
+		"https://prometheus103.example.com:9090",
+	}, urls)
+	assert.False(t, f.Changed())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"targets": ["prometheus104.example.com:9090"]}]`), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.True(t, f.Changed())
+
+	urls, err = f.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://prometheus104.example.com:9090"}, urls)
+}
+
+func TestHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `[{"targets": ["prometheus101.example.com:9090", "https://prometheus102.example.com"]}]`)
+	}))
+	defer s.Close()
+
+	urls, err := NewHTTP(s.URL, "http").Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://prometheus101.example.com:9090", "https://prometheus102.example.com"}, urls)
+}
+
+type fakeDiscoverer struct {
+	urls []string
+	err  error
+}
+
+func (f *fakeDiscoverer) Discover(context.Context) ([]string, error) {
+	return f.urls, f.err
+}
+
+func (f *fakeDiscoverer) String() string {
+	return "fake"
+}
+
+func TestManager(t *testing.T) {
+	d := &fakeDiscoverer{
+		urls: []string{"http://prometheus102.example.com:9090", "http://prometheus101.example.com:9090"},
+	}
+
+	m := NewManager(zap.NewNop().Sugar(), []string{"http://prometheus101.example.com:9090"}, time.Minute, time.Second, d)
+
+	expected := []string{"http://prometheus101.example.com:9090", "http://prometheus102.example.com:9090"}
+
+	urls, err := m.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, urls)
+
+	t.Run("the last result is used if the discovery fails", func(t *testing.T) {
+		d.urls, d.err = nil, errors.New("discovery failed")
+
+		urls, err := m.Discover(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, expected, urls)
+	})
+
+	t.Run("an error is returned if the discovery was never successful", func(t *testing.T) {
+		m := NewManager(zap.NewNop().Sugar(), nil, time.Minute, time.Second, d)
+
+		_, err := m.Discover(context.Background())
+		assert.Error(t, err)
+	})
+}
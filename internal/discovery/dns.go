@@ -0,0 +1,66 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS discovers prometheus servers by DNS SRV records or, if the name contains
+// a port, by A/AAAA records.
+type DNS struct {
+	name     string
+	scheme   string
+	resolver *net.Resolver
+}
+
+var _ Discoverer = (*DNS)(nil)
+
+// NewDNS creates a new DNS discoverer. The name is either a SRV record
+// (_prometheus._tcp.example.com) or a host name with port (prometheus.example.com:9090).
+func NewDNS(name, scheme string) *DNS {
+	return &DNS{
+		name:     name,
+		scheme:   scheme,
+		resolver: net.DefaultResolver,
+	}
+}
+
+// Discover implements Discoverer.
+func (d *DNS) Discover(ctx context.Context) ([]string, error) {
+	if host, port, err := net.SplitHostPort(d.name); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := d.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		urls := make([]string, 0, len(addrs))
+		for _, a := range addrs {
+			urls = append(urls, serverURL(d.scheme, a, p))
+		}
+
+		return urls, nil
+	}
+
+	_, records, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, r := range records {
+		urls = append(urls, serverURL(d.scheme, strings.TrimSuffix(r.Target, "."), int(r.Port)))
+	}
+
+	return urls, nil
+}
+
+func (d *DNS) String() string {
+	return "dns:" + d.name
+}
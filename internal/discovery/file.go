@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// targetGroup is a group of servers in the prometheus file_sd and http_sd format.
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// urls returns the server URLs of the group. The scheme is taken from the __scheme__
+// label or the default scheme. Targets with a scheme are returned unchanged.
+func (g targetGroup) urls(scheme string) []string {
+	if s, ok := g.Labels[schemeLabelName]; ok && s != "" {
+		scheme = s
+	}
+
+	urls := make([]string, 0, len(g.Targets))
+
+	for _, t := range g.Targets {
+		if strings.Contains(t, "://") {
+			urls = append(urls, t)
+			continue
+		}
+
+		urls = append(urls, scheme+"://"+t)
+	}
+
+	return urls
+}
+
+// File discovers prometheus servers from a file in the prometheus file_sd format
+// (json or yaml). The file is only read again if its modification time changes.
+type File struct {
+	path   string
+	scheme string
+
+	mu      sync.Mutex
+	modTime time.Time
+	urls    []string
+}
+
+var _ Discoverer = (*File)(nil)
+
+// NewFile creates a new File discoverer.
+func NewFile(path, scheme string) *File {
+	return &File{
+		path:   path,
+		scheme: scheme,
+	}
+}
+
+// Discover implements Discoverer.
+func (f *File) Discover(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.urls != nil && fi.ModTime().Equal(f.modTime) {
+		return f.urls, nil
+	}
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []targetGroup{}
+
+	switch filepath.Ext(f.path) {
+	case ".json":
+		err = json.Unmarshal(b, &groups)
+	case ".yml", ".yaml":
+		err = yaml.UnmarshalStrict(b, &groups)
+	default:
+		return nil, fmt.Errorf("unsupported file extension of %s (json|yml|yaml)", f.path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+
+	urls := []string{}
+	for _, g := range groups {
+		urls = append(urls, g.urls(f.scheme)...)
+	}
+
+	f.urls, f.modTime = urls, fi.ModTime()
+
+	return urls, nil
+}
+
+// Changed returns true, if the modification time of the file has changed
+// since the last discovery. Errors are reported on the next discovery.
+func (f *File) Changed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+
+	return !fi.ModTime().Equal(f.modTime)
+}
+
+func (f *File) String() string {
+	return "file:" + f.path
+}
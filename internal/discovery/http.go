@@ -0,0 +1,64 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HTTP discovers prometheus servers from a http endpoint returning the servers
+// in the prometheus http_sd format.
+type HTTP struct {
+	url    string
+	scheme string
+	client *http.Client
+}
+
+var _ Discoverer = (*HTTP)(nil)
+
+// NewHTTP creates a new HTTP discoverer.
+func NewHTTP(u, scheme string) *HTTP {
+	return &HTTP{
+		url:    u,
+		scheme: scheme,
+		client: &http.Client{},
+	}
+}
+
+// Discover implements Discoverer.
+func (h *HTTP) Discover(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	groups := []targetGroup{}
+	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	urls := []string{}
+	for _, g := range groups {
+		urls = append(urls, g.urls(h.scheme)...)
+	}
+
+	return urls, nil
+}
+
+func (h *HTTP) String() string {
+	return "http:" + h.url
+}
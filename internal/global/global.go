@@ -0,0 +1,183 @@
+// Package global evaluates PromQL queries over the series of all prometheus servers.
+//
+// The queries of the prometheus servers are evaluated by each server on its own, so
+// aggregations like sum(up) by (job) are only correct per server. The Engine evaluates
+// the queries with the prometheus PromQL engine on the raw samples of all servers
+// instead. The raw samples are requested from every server with an instant query of
+// a range vector selector for each selector of the query.
+package global
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+)
+
+const (
+	defaultMaxSamples     = 50000000
+	defaultLookbackDelta  = 5 * time.Minute
+	defaultSubqueryStepMs = int64(time.Minute / time.Millisecond)
+	defaultQueryTimeout   = 2 * time.Minute
+)
+
+// Engine evaluates PromQL queries over the series of all prometheus servers.
+type Engine struct {
+	client        *prometheus.Client
+	engine        *promql.Engine
+	timeout       time.Duration
+	deduplicate   bool
+	replicaLabels []string
+}
+
+// Option is a functional option to configure the engine.
+type Option func(*Engine)
+
+// WithTimeout sets the maximum duration of the evaluation of a query.
+func WithTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.timeout = d
+	}
+}
+
+// WithDeduplicate deduplicates the series of HA replicas before the evaluation. The
+// series are identical if their labels without the server and replicaLabels are equal.
+func WithDeduplicate(deduplicate bool, replicaLabels ...string) Option {
+	return func(e *Engine) {
+		e.deduplicate = deduplicate
+		e.replicaLabels = replicaLabels
+	}
+}
+
+// New creates a new engine.
+func New(client *prometheus.Client, opts ...Option) *Engine {
+	e := &Engine{
+		client:  client,
+		timeout: defaultQueryTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.engine = promql.NewEngine(promql.EngineOpts{
+		MaxSamples:           defaultMaxSamples,
+		Timeout:              e.timeout,
+		LookbackDelta:        defaultLookbackDelta,
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+		NoStepSubqueryIntervalFn: func(int64) int64 {
+			return defaultSubqueryStepMs
+		},
+	})
+
+	return e
+}
+
+// Query evaluates an instant query.
+func (e *Engine) Query(ctx context.Context, query string, ts time.Time) (model.Value, v1.Warnings, error) {
+	q, err := e.engine.NewInstantQuery(e.queryable(), nil, query, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.exec(ctx, q)
+}
+
+// QueryRange evaluates a range query.
+func (e *Engine) QueryRange(ctx context.Context, query string, r v1.Range) (model.Value, v1.Warnings, error) {
+	q, err := e.engine.NewRangeQuery(e.queryable(), nil, query, r.Start, r.End, r.Step)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.exec(ctx, q)
+}
+
+func (e *Engine) exec(ctx context.Context, q promql.Query) (model.Value, v1.Warnings, error) {
+	defer q.Close()
+
+	res := q.Exec(ctx)
+
+	warnings := make(v1.Warnings, 0, len(res.Warnings))
+	for _, w := range res.Warnings {
+		warnings = append(warnings, w.Error())
+	}
+
+	if res.Err != nil {
+		return nil, warnings, res.Err
+	}
+
+	v, err := toModelValue(res.Value)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return v, warnings, nil
+}
+
+func (e *Engine) queryable() *queryable {
+	q := &queryable{client: e.client}
+
+	if e.deduplicate {
+		q.replicaLabels = e.replicaLabels
+		q.deduplicate = true
+	}
+
+	return q
+}
+
+// toModelValue converts a result of the PromQL engine to the API model.
+func toModelValue(v interface{}) (model.Value, error) {
+	switch v := v.(type) {
+	case promql.Vector:
+		vector := make(model.Vector, 0, len(v))
+
+		for _, s := range v {
+			vector = append(vector, &model.Sample{
+				Metric:    toMetric(s.Metric),
+				Value:     model.SampleValue(s.V),
+				Timestamp: model.Time(s.T),
+			})
+		}
+
+		return vector, nil
+	case promql.Matrix:
+		matrix := make(model.Matrix, 0, len(v))
+
+		for _, s := range v {
+			values := make([]model.SamplePair, 0, len(s.Points))
+			for _, p := range s.Points {
+				values = append(values, model.SamplePair{Timestamp: model.Time(p.T), Value: model.SampleValue(p.V)})
+			}
+
+			matrix = append(matrix, &model.SampleStream{
+				Metric: toMetric(s.Metric),
+				Values: values,
+			})
+		}
+
+		return matrix, nil
+	case promql.Scalar:
+		return &model.Scalar{Value: model.SampleValue(v.V), Timestamp: model.Time(v.T)}, nil
+	case promql.String:
+		return &model.String{Value: v.V, Timestamp: model.Time(v.T)}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected result type %T", v)
+}
+
+func toMetric(l labels.Labels) model.Metric {
+	m := make(model.Metric, len(l))
+
+	for _, lbl := range l {
+		m[model.LabelName(lbl.Name)] = model.LabelValue(lbl.Value)
+	}
+
+	return m
+}
@@ -0,0 +1,144 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ts = 1628763000
+
+func TestEngine(t *testing.T) {
+	queries := make(chan string, 100)
+
+	// upstream returns the up series of the jobs with samples every 15s of the last 5 minutes
+	upstream := func(replica string, jobs ...string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/query" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			queries <- r.FormValue("query")
+
+			result := ""
+
+			for i, job := range jobs {
+				values := ""
+
+				for t := ts - 300; t <= ts; t += 15 {
+					if values != "" {
+						values += ","
+					}
+
+					values += fmt.Sprintf(`[%d,"1"]`, t)
+				}
+
+				if i > 0 {
+					result += ","
+				}
+
+				result += fmt.Sprintf(`{"metric":{"__name__":"up","job":"%s","replica":"%s"},"values":[%s]}`, job, replica, values)
+			}
+
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, result)
+		}))
+	}
+
+	s1 := upstream("a", "node", "web")
+	defer s1.Close()
+
+	s2 := upstream("b", "node")
+	defer s2.Close()
+
+	c, err := prometheus.New([]string{s1.URL, s2.URL}, prometheus.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	t.Run("aggregations must be evaluated over all servers", func(t *testing.T) {
+		v, warnings, err := New(c).Query(context.Background(), `sum(up) by (job)`, time.Unix(ts, 0))
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+
+		vector, ok := v.(model.Vector)
+		require.True(t, ok)
+		require.Len(t, vector, 2)
+
+		result := map[model.LabelValue]model.SampleValue{}
+		for _, s := range vector {
+			result[s.Metric["job"]] = s.Value
+		}
+
+		assert.Equal(t, map[model.LabelValue]model.SampleValue{"node": 2, "web": 1}, result)
+
+		q := <-queries
+		assert.Regexp(t, `^\{__name__="up"\}\[\d+ms\]$`, q)
+	})
+
+	t.Run("the series of replicas must be deduplicated", func(t *testing.T) {
+		e := New(c, WithDeduplicate(true, "replica"))
+
+		v, _, err := e.Query(context.Background(), `sum(up) by (job)`, time.Unix(ts, 0))
+		require.NoError(t, err)
+
+		vector := v.(model.Vector)
+		require.Len(t, vector, 2)
+
+		for _, s := range vector {
+			assert.Equal(t, model.SampleValue(1), s.Value)
+		}
+	})
+
+	t.Run("the server label must be matched by promi", func(t *testing.T) {
+		u, err := url.Parse(s2.URL)
+		require.NoError(t, err)
+
+		v, _, err := New(c).Query(context.Background(), fmt.Sprintf(`up{%s="%s"}`, prometheus.SourceLabelName, u.Host), time.Unix(ts, 0))
+		require.NoError(t, err)
+
+		vector := v.(model.Vector)
+		require.Len(t, vector, 1)
+		assert.Equal(t, model.LabelValue("b"), vector[0].Metric["replica"])
+	})
+
+	t.Run("range queries must be evaluated", func(t *testing.T) {
+		v, _, err := New(c).QueryRange(context.Background(), `count(up)`, v1.Range{
+			Start: time.Unix(ts-60, 0),
+			End:   time.Unix(ts, 0),
+			Step:  30 * time.Second,
+		})
+		require.NoError(t, err)
+
+		matrix, ok := v.(model.Matrix)
+		require.True(t, ok)
+		require.Len(t, matrix, 1)
+		require.Len(t, matrix[0].Values, 3)
+
+		for _, p := range matrix[0].Values {
+			assert.Equal(t, model.SampleValue(3), p.Value)
+		}
+	})
+
+	t.Run("range vectors must contain the samples of the range only", func(t *testing.T) {
+		v, _, err := New(c).Query(context.Background(), `count_over_time(up{job="web"}[1m])`, time.Unix(ts, 0))
+		require.NoError(t, err)
+
+		vector := v.(model.Vector)
+		require.Len(t, vector, 1)
+		assert.Equal(t, model.SampleValue(5), vector[0].Value, "the samples of the last 5 minutes must not be selected")
+	})
+
+	t.Run("a selector must not only match the server label", func(t *testing.T) {
+		_, _, err := New(c).Query(context.Background(), `{`+prometheus.SourceLabelName+`="a"}`, time.Unix(ts, 0))
+		require.Error(t, err)
+	})
+}
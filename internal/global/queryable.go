@@ -0,0 +1,242 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+)
+
+// queryable is a storage.Queryable over the raw samples of all prometheus servers.
+type queryable struct {
+	client        *prometheus.Client
+	deduplicate   bool
+	replicaLabels []string
+}
+
+func (q *queryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &querier{queryable: q, ctx: ctx, mint: mint, maxt: maxt}, nil
+}
+
+type querier struct {
+	*queryable
+	ctx        context.Context
+	mint, maxt int64
+}
+
+// Select requests the raw samples of the series matching the matchers of all servers
+// with an instant query of a range vector selector at the end of the selected range.
+// Matchers on the server label are applied by promi. The samples of [start, end] of the
+// hints are returned.
+func (q *querier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	start, end := q.mint, q.maxt
+	if hints != nil {
+		start, end = hints.Start, hints.End
+	}
+
+	selector, err := selector(matchers)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	// a range selector does not select the samples at end-range, the range is extended
+	// by one millisecond to include the samples at start
+	query := fmt.Sprintf("%s[%dms]", selector, end-start+1)
+
+	v, warnings, err := q.client.Query(q.ctx, query, timestamp(end))
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	matrix, ok := v.(model.Matrix)
+	if !ok {
+		return storage.ErrSeriesSet(fmt.Errorf("unexpected result type %s of %s", v.Type(), query))
+	}
+
+	if q.deduplicate {
+		matrix = prometheus.DeduplicateValue(matrix, q.replicaLabels...).(model.Matrix)
+	}
+
+	s := &seriesSet{index: -1, warnings: toWarnings(warnings)}
+
+	for _, stream := range matrix {
+		lset := toLabels(stream.Metric)
+		if !matches(lset, matchers) {
+			continue
+		}
+
+		samples := make([]tsdbutil.Sample, 0, len(stream.Values))
+		for _, p := range stream.Values {
+			// servers before prometheus 3.0 select the samples at end-range as well
+			if int64(p.Timestamp) < start || int64(p.Timestamp) > end {
+				continue
+			}
+
+			samples = append(samples, sample{t: int64(p.Timestamp), v: float64(p.Value)})
+		}
+
+		s.series = append(s.series, storage.NewListSeries(lset, samples))
+	}
+
+	if sortSeries {
+		sort.Slice(s.series, func(i, j int) bool {
+			return labels.Compare(s.series[i].Labels(), s.series[j].Labels()) < 0
+		})
+	}
+
+	return s
+}
+
+func (q *querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	match, err := matchParam(matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, warnings, err := q.client.LabelValues(q.ctx, name, match, timestamp(q.mint), timestamp(q.maxt))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, string(v))
+	}
+
+	return result, toWarnings(warnings), nil
+}
+
+func (q *querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	match, err := matchParam(matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names, warnings, err := q.client.LabelNames(q.ctx, match, timestamp(q.mint), timestamp(q.maxt))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return names, toWarnings(warnings), nil
+}
+
+func (q *querier) Close() error {
+	return nil
+}
+
+// selector returns the vector selector of the matchers without the matchers on the
+// server label, which the prometheus servers do not know.
+func selector(matchers []*labels.Matcher) (string, error) {
+	s := make([]string, 0, len(matchers))
+
+	for _, m := range matchers {
+		if m.Name == prometheus.SourceLabelName {
+			continue
+		}
+
+		s = append(s, m.String())
+	}
+
+	if len(s) == 0 {
+		return "", errors.New("a selector must contain at least one matcher besides " + prometheus.SourceLabelName)
+	}
+
+	return "{" + strings.Join(s, ",") + "}", nil
+}
+
+// matchParam returns the match[] parameter of the matchers.
+func matchParam(matchers []*labels.Matcher) ([]string, error) {
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+
+	s, err := selector(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{s}, nil
+}
+
+// matches returns true if the labels match all matchers.
+func matches(l labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(l.Get(m.Name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toLabels(m model.Metric) labels.Labels {
+	l := make(labels.Labels, 0, len(m))
+
+	for n, v := range m {
+		l = append(l, labels.Label{Name: string(n), Value: string(v)})
+	}
+
+	sort.Sort(l)
+
+	return l
+}
+
+func toWarnings(w v1.Warnings) storage.Warnings {
+	warnings := make(storage.Warnings, 0, len(w))
+
+	for _, s := range w {
+		warnings = append(warnings, errors.New(s))
+	}
+
+	return warnings
+}
+
+func timestamp(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func (s sample) T() int64 {
+	return s.t
+}
+
+func (s sample) V() float64 {
+	return s.v
+}
+
+// seriesSet is a storage.SeriesSet of a list of series.
+type seriesSet struct {
+	series   []storage.Series
+	index    int
+	warnings storage.Warnings
+}
+
+func (s *seriesSet) Next() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *seriesSet) At() storage.Series {
+	return s.series[s.index]
+}
+
+func (s *seriesSet) Err() error {
+	return nil
+}
+
+func (s *seriesSet) Warnings() storage.Warnings {
+	return s.warnings
+}
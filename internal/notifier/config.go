@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The payload formats of a route.
+const (
+	FormatJSON         = "json"
+	FormatAlertmanager = "alertmanager"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultTimeout  = 10 * time.Second
+)
+
+// Config is the notifier configuration.
+//
+//	interval: 1m
+//	for: 5m
+//	routes:
+//	  - selector: team=infra
+//	    urls:
+//	      - http://alertmanager.example.com/api/v2/alerts
+//	    format: alertmanager
+type Config struct {
+	// Interval is the interval in which the target health is checked.
+	Interval time.Duration `yaml:"interval"`
+	// For is the duration a target has to be down before a notification is sent.
+	For time.Duration `yaml:"for"`
+	// Timeout is the timeout for a webhook request.
+	Timeout time.Duration `yaml:"timeout"`
+	Routes  []Route       `yaml:"routes"`
+}
+
+// Route sends the notifications of all targets matching the selector to the urls.
+type Route struct {
+	// Selector is a k8s style label selector. An empty selector matches all targets.
+	Selector string   `yaml:"selector"`
+	URLs     []string `yaml:"urls"`
+	// Format is the payload format (json|alertmanager).
+	Format string `yaml:"format"`
+
+	matches prometheus.TargetFilterFunc
+}
+
+// LoadConfig reads and validates the configuration file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path) //nolint:gosec // the path is configured by the operator
+	if err != nil {
+		return nil, err
+	}
+
+	c := Config{}
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse notifier config %s: %w", path, err)
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid notifier config %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+func (c *Config) validate() error {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+
+	if c.Interval < 0 || c.For < 0 || c.Timeout < 0 {
+		return errors.New("durations must not be negative")
+	}
+
+	if len(c.Routes) == 0 {
+		return errors.New("no routes configured")
+	}
+
+	for i := range c.Routes {
+		r := &c.Routes[i]
+
+		if r.Format == "" {
+			r.Format = FormatJSON
+		}
+
+		if r.Format != FormatJSON && r.Format != FormatAlertmanager {
+			return fmt.Errorf("route %d: unknown format '%s'", i, r.Format)
+		}
+
+		if len(r.URLs) == 0 {
+			return fmt.Errorf("route %d: no urls configured", i)
+		}
+
+		for _, u := range r.URLs {
+			if _, err := url.ParseRequestURI(u); err != nil {
+				return fmt.Errorf("route %d: %w", i, err)
+			}
+		}
+
+		sel, err := labels.Parse(r.Selector)
+		if err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+
+		r.matches = prometheus.TargetBySelector(sel)
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+// Package notifier sends webhook notifications on target health transitions.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+)
+
+// The notification states.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+const alertName = "PromiTargetDown"
+
+// TargetsFunc returns the current targets.
+type TargetsFunc func(context.Context) (prometheus.Targets, error)
+
+// Notifier watches the target health and notifies the configured routes
+// if a target is down for the configured duration and when it recovers.
+type Notifier struct {
+	l       *zap.SugaredLogger
+	cfg     *Config
+	targets TargetsFunc
+	client  *http.Client
+	states  map[string]*state
+	now     func() time.Time
+}
+
+// state is the health state of a single target that is down or whose firing
+// notification is not resolved yet.
+type state struct {
+	target    prometheus.Target
+	down      bool
+	downSince time.Time
+	// notified are the receivers that received a firing notification.
+	notified map[string]bool
+}
+
+// Notification is the json payload of a single notification.
+type Notification struct {
+	Status    string         `json:"status"`
+	Server    string         `json:"server"`
+	Job       string         `json:"job"`
+	ScrapeURL string         `json:"scrapeUrl"`
+	Labels    model.LabelSet `json:"labels"`
+	LastError string         `json:"lastError,omitempty"`
+	Since     time.Time      `json:"since"`
+	Time      time.Time      `json:"time"`
+}
+
+// New creates a new notifier.
+func New(l *zap.SugaredLogger, cfg *Config, targets TargetsFunc) *Notifier {
+	return &Notifier{
+		l:       l,
+		cfg:     cfg,
+		targets: targets,
+		client:  &http.Client{},
+		states:  map[string]*state{},
+		now:     time.Now,
+	}
+}
+
+// Run checks the target health in the configured interval until the context
+// is canceled.
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		n.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Notifier) check(ctx context.Context) {
+	targets, err := n.targets(ctx)
+	if err != nil {
+		n.l.Warnw("failed to get targets for notifications", "err", err)
+		return
+	}
+
+	now := n.now()
+
+	n.update(targets, now)
+
+	for i, r := range n.cfg.Routes {
+		for _, u := range r.URLs {
+			receiver := fmt.Sprintf("%d|%s", i, u)
+
+			notifications, keys := n.notifications(r, receiver, now)
+			if len(notifications) == 0 {
+				continue
+			}
+
+			if err := n.send(ctx, u, r.Format, notifications); err != nil {
+				n.l.Errorw("failed to send notifications", "url", u, "err", err)
+				continue
+			}
+
+			for j, key := range keys {
+				n.states[key].notified[receiver] = notifications[j].Status == StatusFiring
+			}
+		}
+	}
+
+	n.cleanup()
+}
+
+// update updates the health states with the current targets. Targets that are not
+// down anymore or that disappeared are kept until their resolved notifications are sent.
+// The states of the targets of failing servers are not changed, because their targets
+// are missing.
+func (n *Notifier) update(targets prometheus.Targets, now time.Time) {
+	failed := map[string]bool{}
+
+	for i := range targets {
+		if targets[i].IsScraper() && targets[i].Health == v1.HealthBad {
+			failed[targets[i].Server()] = true
+		}
+	}
+
+	for _, s := range n.states {
+		if s.target.IsScraper() || !failed[s.target.Server()] {
+			s.down = false
+		}
+	}
+
+	for i := range targets {
+		t := targets[i]
+		down := t.Health == v1.HealthBad
+
+		s, ok := n.states[t.Key()]
+		if !ok {
+			if !down {
+				continue
+			}
+
+			s = &state{downSince: now, notified: map[string]bool{}}
+			n.states[t.Key()] = s
+		}
+
+		s.target = t
+		s.down = down
+	}
+}
+
+// notifications returns the notifications of a route for the receiver and the keys of
+// their targets. Firing notifications are returned for targets of the route that are
+// down for the configured duration, resolved notifications for targets the receiver was
+// notified about and that are up again or disappeared. Firing notifications of the
+// alertmanager format are resent.
+func (n *Notifier) notifications(r Route, receiver string, now time.Time) ([]Notification, []string) {
+	resend := r.Format == FormatAlertmanager
+
+	type keyed struct {
+		key          string
+		notification Notification
+	}
+
+	result := []keyed{}
+
+	for key, s := range n.states {
+		notified := s.notified[receiver]
+
+		switch {
+		case s.down && r.matches(s.target):
+			if (!notified && now.Sub(s.downSince) >= n.cfg.For) || (notified && resend) {
+				result = append(result, keyed{key, notification(StatusFiring, &s.target, s.downSince, now)})
+			}
+		case !s.down && notified:
+			result = append(result, keyed{key, notification(StatusResolved, &s.target, s.downSince, now)})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].notification.Labels.Before(result[j].notification.Labels)
+	})
+
+	notifications := make([]Notification, 0, len(result))
+	keys := make([]string, 0, len(result))
+
+	for _, k := range result {
+		notifications = append(notifications, k.notification)
+		keys = append(keys, k.key)
+	}
+
+	return notifications, keys
+}
+
+// cleanup forgets the targets that are not down and whose notifications are resolved.
+func (n *Notifier) cleanup() {
+	for key, s := range n.states {
+		for receiver, notified := range s.notified {
+			if !notified {
+				delete(s.notified, receiver)
+			}
+		}
+
+		if !s.down && len(s.notified) == 0 {
+			delete(n.states, key)
+		}
+	}
+}
+
+func notification(status string, t *prometheus.Target, since, now time.Time) Notification {
+	return Notification{
+		Status:    status,
+		Server:    t.Server(),
+		Job:       t.Job(),
+		ScrapeURL: t.ScrapeURL,
+		Labels:    t.Labels,
+		LastError: t.LastError,
+		Since:     since,
+		Time:      now,
+	}
+}
+
+// alertmanagerAlert is an alert of the alertmanager v2 api.
+type alertmanagerAlert struct {
+	Labels       model.LabelSet `json:"labels"`
+	Annotations  model.LabelSet `json:"annotations"`
+	StartsAt     time.Time      `json:"startsAt"`
+	EndsAt       *time.Time     `json:"endsAt,omitempty"`
+	GeneratorURL string         `json:"generatorURL,omitempty"`
+}
+
+func payload(format string, notifications []Notification) interface{} {
+	if format != FormatAlertmanager {
+		return struct {
+			Notifications []Notification `json:"notifications"`
+		}{
+			Notifications: notifications,
+		}
+	}
+
+	alerts := make([]alertmanagerAlert, 0, len(notifications))
+
+	for _, n := range notifications {
+		a := alertmanagerAlert{
+			Labels: n.Labels.Merge(model.LabelSet{
+				model.AlertNameLabel: alertName,
+			}),
+			Annotations: model.LabelSet{
+				"summary":    model.LabelValue(fmt.Sprintf("target %s of job %s is down", n.ScrapeURL, n.Job)),
+				"server":     model.LabelValue(n.Server),
+				"last_error": model.LabelValue(n.LastError),
+			},
+			StartsAt:     n.Since,
+			GeneratorURL: n.ScrapeURL,
+		}
+
+		if n.Status == StatusResolved {
+			endsAt := n.Time
+			a.EndsAt = &endsAt
+		}
+
+		alerts = append(alerts, a)
+	}
+
+	return alerts
+}
+
+func (n *Notifier) send(ctx context.Context, u, format string, notifications []Notification) error {
+	b, err := json.Marshal(payload(format, notifications))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	n.l.Debugw("notifications sent", "url", u, "count", len(notifications))
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNotifier(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Notification
+		fail     bool
+	)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := struct {
+			Notifications []Notification `json:"notifications"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		received = append(received, p.Notifications...)
+	}))
+	defer s.Close()
+
+	cfg := Config{
+		For: 5 * time.Minute,
+		Routes: []Route{
+			{
+				Selector: "team=infra",
+				URLs:     []string{s.URL},
+			},
+		},
+	}
+	require.NoError(t, cfg.validate())
+
+	health := map[string]v1.HealthStatus{}
+	gone := map[string]bool{}
+	serverDown := false
+	targets := func(context.Context) (prometheus.Targets, error) {
+		scraper := prometheus.Target{
+			ActiveTarget: v1.ActiveTarget{
+				ScrapePool: "promi_scrape_sources",
+				ScrapeURL:  "prom1:9090",
+				Health:     v1.HealthGood,
+				Labels:     model.LabelSet{"instance": "prom1:9090"},
+			},
+		}
+
+		if serverDown {
+			scraper.Health = v1.HealthBad
+			return prometheus.Targets{scraper}, nil
+		}
+
+		targets := prometheus.Targets{scraper}
+
+		for _, team := range []string{"infra", "app"} {
+			if gone[team] {
+				continue
+			}
+
+			targets = append(targets, prometheus.Target{
+				ActiveTarget: v1.ActiveTarget{
+					ScrapeURL: "http://" + team + ".example.com/metrics",
+					Health:    health[team],
+					Labels: model.LabelSet{
+						"job":                      "node",
+						"team":                     model.LabelValue(team),
+						prometheus.SourceLabelName: "prom1:9090",
+					},
+				},
+			})
+		}
+
+		return targets, nil
+	}
+
+	now := time.Now()
+	n := New(zap.NewNop().Sugar(), &cfg, targets)
+	n.now = func() time.Time { return now }
+
+	step := func(d time.Duration, infra, app v1.HealthStatus) []Notification {
+		now = now.Add(d)
+		health["infra"], health["app"] = infra, app
+
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		n.check(context.Background())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return received
+	}
+
+	assert.Empty(t, step(0, v1.HealthBad, v1.HealthBad), "targets down for less than 'for'")
+	assert.Empty(t, step(time.Minute, v1.HealthBad, v1.HealthBad), "targets down for less than 'for'")
+
+	notifications := step(5*time.Minute, v1.HealthBad, v1.HealthBad)
+	require.Len(t, notifications, 1, "only routed targets are notified")
+	assert.Equal(t, StatusFiring, notifications[0].Status)
+	assert.Equal(t, "http://infra.example.com/metrics", notifications[0].ScrapeURL)
+
+	assert.Empty(t, step(time.Minute, v1.HealthBad, v1.HealthBad), "firing notification is only sent once")
+
+	notifications = step(time.Minute, v1.HealthGood, v1.HealthGood)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StatusResolved, notifications[0].Status)
+
+	assert.Empty(t, step(time.Minute, v1.HealthGood, v1.HealthGood))
+
+	t.Run("failed notifications are retried", func(t *testing.T) {
+		mu.Lock()
+		fail = true
+		mu.Unlock()
+
+		assert.Empty(t, step(0, v1.HealthBad, v1.HealthGood))
+		assert.Empty(t, step(5*time.Minute, v1.HealthBad, v1.HealthGood), "sending fails")
+
+		mu.Lock()
+		fail = false
+		mu.Unlock()
+
+		notifications := step(time.Minute, v1.HealthBad, v1.HealthGood)
+		require.Len(t, notifications, 1, "the failed firing notification must be sent again")
+		assert.Equal(t, StatusFiring, notifications[0].Status)
+
+		mu.Lock()
+		fail = true
+		mu.Unlock()
+
+		assert.Empty(t, step(time.Minute, v1.HealthGood, v1.HealthGood), "sending fails")
+
+		mu.Lock()
+		fail = false
+		mu.Unlock()
+
+		notifications = step(time.Minute, v1.HealthGood, v1.HealthGood)
+		require.Len(t, notifications, 1, "the failed resolved notification must be sent again")
+		assert.Equal(t, StatusResolved, notifications[0].Status)
+
+		assert.Empty(t, step(time.Minute, v1.HealthGood, v1.HealthGood))
+	})
+
+	t.Run("targets of a failing server are not resolved", func(t *testing.T) {
+		assert.Empty(t, step(0, v1.HealthBad, v1.HealthGood))
+
+		notifications := step(5*time.Minute, v1.HealthBad, v1.HealthGood)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, StatusFiring, notifications[0].Status)
+
+		serverDown = true
+
+		assert.Empty(t, step(time.Minute, v1.HealthBad, v1.HealthGood), "the server is down, not the target")
+
+		serverDown = false
+
+		assert.Empty(t, step(time.Minute, v1.HealthBad, v1.HealthGood), "the target is still down")
+
+		notifications = step(time.Minute, v1.HealthGood, v1.HealthGood)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, StatusResolved, notifications[0].Status)
+	})
+
+	t.Run("disappeared targets are resolved", func(t *testing.T) {
+		notifications := step(5*time.Minute, v1.HealthBad, v1.HealthGood)
+		assert.Empty(t, notifications)
+
+		notifications = step(5*time.Minute, v1.HealthBad, v1.HealthGood)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, StatusFiring, notifications[0].Status)
+
+		gone["infra"] = true
+
+		notifications = step(time.Minute, v1.HealthBad, v1.HealthGood)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, StatusResolved, notifications[0].Status)
+		assert.Equal(t, "http://infra.example.com/metrics", notifications[0].ScrapeURL)
+
+		assert.Empty(t, step(time.Minute, v1.HealthBad, v1.HealthGood))
+		assert.Empty(t, n.states, "resolved targets must be forgotten")
+	})
+}
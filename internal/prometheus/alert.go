@@ -2,17 +2,24 @@ package prometheus
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
-	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
 	alertNameLabelName = "alertname"
+	severityLabelName  = "severity"
 	dlftJobName        = "default"
 )
 
@@ -37,7 +44,103 @@ func (a Alert) Row() []string {
 		col = color.New(color.FgYellow).SprintFunc()
 	}
 
-	return []string{string(a.Labels[sourceLabelName]), a.Job(), a.Name(), time.Since(a.ActiveAt).String(), col(string(a.State))}
+	return []string{string(a.Labels[sourceLabelName]), a.Job(), a.Name(), time.Since(a.ActiveAt).String(), col(string(a.State)) + staleness(a.Annotations)}
+}
+
+// Annotation returns the value of the annotation or "-" if the alert has no such
+// annotation.
+func (a Alert) Annotation(name string) string {
+	v, ok := a.Annotations[model.LabelName(name)]
+	if !ok || v == "" {
+		return "-"
+	}
+
+	return string(v)
+}
+
+// Describe writes all details of the alerts.
+func (a Alerts) Describe(w io.Writer) error {
+	for i, alert := range a {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+
+		fmt.Fprintf(tw, "Name:\t%s\n", alert.Name())
+		fmt.Fprintf(tw, "Server:\t%s\n", alert.Server())
+		fmt.Fprintf(tw, "State:\t%s\n", alert.State)
+		fmt.Fprintf(tw, "Active At:\t%s (%s ago)\n", alert.ActiveAt.Format(time.RFC3339), time.Since(alert.ActiveAt).Round(time.Second))
+		fmt.Fprintf(tw, "Value:\t%s\n", alert.Value)
+		fmt.Fprintln(tw, "Labels:\t")
+		writeLabelSet(tw, alert.Labels)
+		fmt.Fprintln(tw, "Annotations:\t")
+		writeLabelSet(tw, alert.Annotations)
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLabelSet writes the sorted labels indented.
+func writeLabelSet(w io.Writer, ls model.LabelSet) {
+	names := make(model.LabelNames, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+
+	sort.Sort(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s:\t%s\n", name, ls[name])
+	}
+}
+
+// AnnotatedAlert is an alert with additional annotation columns in the table output.
+type AnnotatedAlert struct {
+	Alert
+	annotations []string
+}
+
+// WithAnnotations returns the alerts with the annotations as additional columns.
+func (a Alerts) WithAnnotations(names ...string) []AnnotatedAlert {
+	alerts := make([]AnnotatedAlert, 0, len(a))
+
+	for _, alert := range a {
+		alerts = append(alerts, AnnotatedAlert{
+			Alert:       alert,
+			annotations: names,
+		})
+	}
+
+	return alerts
+}
+
+// Header represents a alert header with the annotation columns.
+func (a AnnotatedAlert) Header() []string {
+	header := a.Alert.Header()
+
+	for _, name := range a.annotations {
+		header = append(header, strings.ToUpper(name))
+	}
+
+	return header
+}
+
+// Row represents a alert row with the annotation columns.
+func (a AnnotatedAlert) Row() []string {
+	row := a.Alert.Row()
+
+	for _, name := range a.annotations {
+		row = append(row, strings.Join(strings.Fields(a.Annotation(name)), " "))
+	}
+
+	return row
 }
 
 // Job returns the job label value.
@@ -50,6 +153,11 @@ func (a Alert) Job() string {
 	return string(job)
 }
 
+// Server returns the prometheus server of the alert.
+func (a Alert) Server() string {
+	return string(a.Labels[sourceLabelName])
+}
+
 // Name returns the alert name label value.
 func (a Alert) Name() string {
 	job, ok := a.Labels[alertNameLabelName]
@@ -110,42 +218,261 @@ func AlertByState(state v1.AlertState) AlertFilterFunc {
 	}
 }
 
-// Alerts returns all alerts.
-func (c Client) Alerts(ctx context.Context) (Alerts, error) {
-	g, ctx := errgroup.WithContext(ctx)
-	results := make(chan alertResult, len(c.clients))
+// AlertBySelector filters Alerts by labels selector.
+func AlertBySelector(selector labels.Selector) AlertFilterFunc {
+	return func(a Alert) bool {
+		return selector.Matches(k8sLabels{a.Labels})
+	}
+}
+
+// AlertByOlderThan filters Alerts that are active for more than d.
+func AlertByOlderThan(d time.Duration) AlertFilterFunc {
+	now := time.Now()
+
+	return func(a Alert) bool {
+		return now.Sub(a.ActiveAt) > d
+	}
+}
+
+// AlertByNewerThan filters Alerts that became active within d.
+func AlertByNewerThan(d time.Duration) AlertFilterFunc {
+	now := time.Now()
+
+	return func(a Alert) bool {
+		return now.Sub(a.ActiveAt) <= d
+	}
+}
+
+// AlertSortKey is a key to sort alerts.
+type AlertSortKey string
 
-	for server, client := range c.clients {
+// The alert sort keys.
+const (
+	// AlertSortBySince sorts by the active time, the longest active alert first.
+	AlertSortBySince AlertSortKey = "since"
+	// AlertSortByName sorts by alert name.
+	AlertSortByName AlertSortKey = "name"
+	// AlertSortByServer sorts by prometheus server.
+	AlertSortByServer AlertSortKey = "server"
+	// AlertSortBySeverity sorts by the severity label, the most severe alert first.
+	AlertSortBySeverity AlertSortKey = "severity"
+)
+
+// Sort sorts alerts by the keys. Alerts with equal keys are sorted by server, name
+// and labels, so the order is deterministic.
+func (a Alerts) Sort(keys ...AlertSortKey) {
+	keys = append(keys, AlertSortByServer, AlertSortByName)
+
+	sort.SliceStable(a, func(i, j int) bool {
+		for _, key := range keys {
+			if c := a[i].compare(a[j], key); c != 0 {
+				return c < 0
+			}
+		}
+
+		return a[i].Labels.Before(a[j].Labels)
+	})
+}
+
+// compare compares the alerts by key.
+func (a Alert) compare(b Alert, key AlertSortKey) int {
+	switch key {
+	case AlertSortBySince:
+		switch {
+		case a.ActiveAt.Before(b.ActiveAt):
+			return -1
+		case a.ActiveAt.After(b.ActiveAt):
+			return 1
+		}
+
+		return 0
+	case AlertSortByName:
+		return strings.Compare(a.Name(), b.Name())
+	case AlertSortByServer:
+		return strings.Compare(a.Server(), b.Server())
+	case AlertSortBySeverity:
+		if c := severityRank(a.Severity()) - severityRank(b.Severity()); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Severity(), b.Severity())
+	}
+
+	return 0
+}
+
+// Severity returns the severity label value.
+func (a Alert) Severity() string {
+	return string(a.Labels[severityLabelName])
+}
+
+// severityRank returns the rank of common severity label values, the most severe
+// first. Unknown severities are sorted after the known ones.
+func severityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 0
+	case "high", "major", "error":
+		return 1
+	case "warning", "medium":
+		return 2
+	case "minor", "low":
+		return 3
+	case "info", "none":
+		return 4
+	}
+
+	return 5
+}
+
+// Deduplicate merges alerts with identical labels. The replicaLabels and the
+// source label are ignored when comparing the labels and removed from the merged
+// alert. The alert with the earliest ActiveAt wins and the sources of all merged
+// alerts are joined in the sourceLabelName label. The merged alert is stale only if
+// all alerts are stale.
+func (a Alerts) Deduplicate(replicaLabels ...string) Alerts {
+	index := map[model.Fingerprint]int{}
+	sources := [][]string{}
+	alerts := Alerts{}
+
+	for i := range a {
+		alert := a[i]
+		alert.Labels = alert.Labels.Clone()
+		src := string(alert.Labels[sourceLabelName])
+
+		delete(alert.Labels, sourceLabelName)
+
+		for _, l := range replicaLabels {
+			delete(alert.Labels, model.LabelName(l))
+		}
+
+		fp := alert.Labels.Fingerprint()
+
+		j, ok := index[fp]
+		if !ok {
+			index[fp] = len(alerts)
+			sources = append(sources, []string{src})
+			alerts = append(alerts, alert)
+
+			continue
+		}
+
+		sources[j] = append(sources[j], src)
+
+		stale := staleness(alerts[j].Annotations) != "" && staleness(alert.Annotations) != ""
+
+		if alert.ActiveAt.Before(alerts[j].ActiveAt) {
+			alerts[j] = alert
+		}
+
+		if !stale && staleness(alerts[j].Annotations) != "" {
+			alerts[j].Annotations = alerts[j].Annotations.Clone()
+			unmarkStale(alerts[j].Annotations)
+		}
+	}
+
+	for i := range alerts {
+		alerts[i].Labels[sourceLabelName] = model.LabelValue(joinSources(sources[i]...))
+	}
+
+	return alerts
+}
+
+// Alerts returns all alerts. If a server fails and a cache is configured, the
+// cached alerts are returned and marked as stale. Errors of single servers without
+// cached alerts are returned as warnings, an error is returned only if all servers fail.
+func (c *Client) Alerts(ctx context.Context) (Alerts, v1.Warnings, error) {
+	if c.cacheOnly {
+		alerts, err := mergeAlerts(c.cache.alertResults())
+		return alerts, nil, err
+	}
+
+	clients := c.apis()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = []alertResult{}
+		warnings = v1.Warnings{}
+		errs     = map[string]error{}
+	)
+
+	for server, client := range clients {
 		client := client // https://golang.org/doc/faq#closures_and_goroutines
 		server := server
 
-		g.Go(func() error {
-			r, err := client.Alerts(ctx)
-			if err != nil {
-				return err
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			r, err := client.Alerts(serverCtx)
+			r, age := c.cache.alerts(server, r, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil && age == 0 {
+				errs[server] = err
+				warnings = append(warnings, fmt.Sprintf("%s: %s", server, err))
+
+				return
 			}
 
-			results <- alertResult{
+			results = append(results, alertResult{
 				server: server,
 				alert:  r,
-			}
+				age:    age,
+			})
+		}()
+	}
 
-			return nil
-		})
+	wg.Wait()
+
+	c.cache.save()
+
+	if len(results) == 0 && len(errs) > 0 {
+		servers := make([]string, 0, len(errs))
+		for s := range errs {
+			servers = append(servers, s)
+		}
+
+		sort.Strings(servers)
+
+		return nil, nil, fmt.Errorf("%s: %w", servers[0], errs[servers[0]])
 	}
 
-	if err := g.Wait(); err != nil {
-		return nil, err
+	sort.Strings(warnings)
+
+	alerts, err := mergeAlerts(results)
+	if err != nil {
+		return nil, warnings, err
 	}
 
-	close(results)
+	return alerts, warnings, nil
+}
 
+// mergeAlerts merges the alerts of all servers. Alerts of stale results are marked
+// with the staleLabelName and staleAgeLabelName annotations, so that their labels and
+// fingerprints do not change.
+func mergeAlerts(results []alertResult) (Alerts, error) {
 	alerts := Alerts{}
 
-	for r := range results {
+	for _, r := range results {
 		for _, a := range r.alert.Alerts {
 			a.Labels[sourceLabelName] = model.LabelValue(r.server)
 
+			if r.age > 0 {
+				if a.Annotations == nil {
+					a.Annotations = model.LabelSet{}
+				}
+
+				markStale(a.Annotations, r.age)
+			}
+
 			if err := a.Labels.Validate(); err != nil {
 				return nil, err
 			}
@@ -163,4 +490,6 @@ func (c Client) Alerts(ctx context.Context) (Alerts, error) {
 type alertResult struct {
 	alert  v1.AlertsResult
 	server string
+	// age is the age of stale results, zero for fresh results.
+	age time.Duration
 }
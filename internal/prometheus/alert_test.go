@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertDeduplicate(t *testing.T) {
+	now := time.Now()
+
+	alerts := Alerts{
+		Alert{
+			Alert: v1.Alert{
+				ActiveAt: now,
+				State:    v1.AlertStatePending,
+				Labels: model.LabelSet{
+					alertNameLabelName: "alert1",
+					"replica":          "a",
+					sourceLabelName:    "src1",
+				},
+			},
+		},
+		Alert{
+			Alert: v1.Alert{
+				ActiveAt: now.Add(-time.Minute),
+				State:    v1.AlertStateFiring,
+				Labels: model.LabelSet{
+					alertNameLabelName: "alert1",
+					"replica":          "b",
+					sourceLabelName:    "src2",
+				},
+			},
+		},
+		Alert{
+			Alert: v1.Alert{
+				ActiveAt: now,
+				State:    v1.AlertStateFiring,
+				Labels: model.LabelSet{
+					alertNameLabelName: "alert2",
+					"replica":          "a",
+					sourceLabelName:    "src1",
+				},
+			},
+		},
+	}
+
+	t.Run("alerts with different replica labels are not merged without replica labels", func(t *testing.T) {
+		assert.Len(t, alerts.Deduplicate(), 3)
+	})
+
+	t.Run("alerts are merged ignoring replica labels", func(t *testing.T) {
+		dedup := alerts.Deduplicate("replica")
+
+		expected := Alerts{
+			Alert{
+				Alert: v1.Alert{
+					ActiveAt: now.Add(-time.Minute),
+					State:    v1.AlertStateFiring,
+					Labels: model.LabelSet{
+						alertNameLabelName: "alert1",
+						sourceLabelName:    "src1,src2",
+					},
+				},
+			},
+			Alert{
+				Alert: v1.Alert{
+					ActiveAt: now,
+					State:    v1.AlertStateFiring,
+					Labels: model.LabelSet{
+						alertNameLabelName: "alert2",
+						sourceLabelName:    "src1",
+					},
+				},
+			},
+		}
+
+		assert.Equal(t, expected, dedup)
+	})
+
+	t.Run("the original alerts are not modified", func(t *testing.T) {
+		assert.Equal(t, model.LabelValue("src1"), alerts[0].Labels[sourceLabelName])
+		assert.Equal(t, model.LabelValue("a"), alerts[0].Labels["replica"])
+	})
+}
+
+func TestAlertAnnotations(t *testing.T) {
+	alerts := Alerts{
+		Alert{
+			Alert: v1.Alert{
+				ActiveAt: time.Date(2021, 8, 12, 10, 14, 19, 0, time.UTC),
+				State:    v1.AlertStateFiring,
+				Value:    "1e+00",
+				Labels: model.LabelSet{
+					alertNameLabelName: "InstanceDown",
+					sourceLabelName:    "src1",
+				},
+				Annotations: model.LabelSet{
+					"summary":     "instance down",
+					"description": "the instance\nis down",
+				},
+			},
+		},
+	}
+
+	t.Run("the annotations must be additional columns", func(t *testing.T) {
+		a := alerts.WithAnnotations("summary", "description", "runbook_url")[0]
+
+		assert.Equal(t, []string{"SUMMARY", "DESCRIPTION", "RUNBOOK_URL"}, a.Header()[len(a.Alert.Header()):])
+		assert.Equal(t, []string{"instance down", "the instance is down", "-"}, a.Row()[len(a.Alert.Row()):])
+	})
+
+	t.Run("describe must contain all labels and annotations", func(t *testing.T) {
+		var b strings.Builder
+
+		assert.NoError(t, alerts.Describe(&b))
+
+		for _, s := range []string{"InstanceDown", "src1", "firing", "2021-08-12T10:14:19Z", "1e+00", "summary:", "instance down", "description:"} {
+			assert.Contains(t, b.String(), s)
+		}
+	})
+}
+
+func TestAlertSort(t *testing.T) {
+	now := time.Now()
+
+	newAlert := func(name, server, severity string, activeAt time.Time) Alert {
+		return Alert{
+			Alert: v1.Alert{
+				ActiveAt: activeAt,
+				Labels: model.LabelSet{
+					alertNameLabelName: model.LabelValue(name),
+					sourceLabelName:    model.LabelValue(server),
+					severityLabelName:  model.LabelValue(severity),
+				},
+			},
+		}
+	}
+
+	alerts := Alerts{
+		newAlert("b", "src2", "warning", now.Add(-time.Minute)),
+		newAlert("a", "src2", "critical", now.Add(-2*time.Hour)),
+		newAlert("c", "src1", "custom", now.Add(-time.Hour)),
+		newAlert("a", "src1", "info", now),
+	}
+
+	names := func(a Alerts) []string {
+		s := []string{}
+		for _, alert := range a {
+			s = append(s, alert.Server()+"/"+alert.Name())
+		}
+
+		return s
+	}
+
+	tt := []struct {
+		keys     []AlertSortKey
+		expected []string
+	}{
+		{nil, []string{"src1/a", "src1/c", "src2/a", "src2/b"}},
+		{[]AlertSortKey{AlertSortByName}, []string{"src1/a", "src2/a", "src2/b", "src1/c"}},
+		{[]AlertSortKey{AlertSortBySince}, []string{"src2/a", "src1/c", "src2/b", "src1/a"}},
+		{[]AlertSortKey{AlertSortBySeverity}, []string{"src2/a", "src2/b", "src1/a", "src1/c"}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(fmt.Sprintf("sort by %v", tc.keys), func(t *testing.T) {
+			a := append(Alerts{}, alerts...)
+			a.Sort(tc.keys...)
+			assert.Equal(t, tc.expected, names(a))
+		})
+	}
+
+	t.Run("filter by age", func(t *testing.T) {
+		assert.Equal(t, []string{"src2/a", "src1/c"}, names(alerts.Filter(AlertByOlderThan(30*time.Minute))))
+		assert.Equal(t, []string{"src2/b", "src1/a"}, names(alerts.Filter(AlertByNewerThan(30*time.Minute))))
+	})
+}
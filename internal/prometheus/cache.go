@@ -0,0 +1,348 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// The labels of stale targets and annotations of stale alerts. They are not part of
+// the keys of the events and of the deduplication.
+const (
+	staleLabelName    = "promi_stale"
+	staleAgeLabelName = "promi_stale_age"
+)
+
+// cacheSaveInterval is the minimal interval between two writes of the cache file.
+const cacheSaveInterval = 30 * time.Second
+
+// Cache keeps the last successful result of each prometheus server. It is
+// used to serve the last known targets and alerts of unreachable servers.
+type Cache struct {
+	path  string
+	grace time.Duration
+
+	mu    sync.Mutex
+	data  cacheData
+	saved time.Time
+}
+
+// cacheData is the on-disk format of the cache.
+type cacheData struct {
+	Targets map[string]*cacheEntry `json:"targets"`
+	Alerts  map[string]*cacheEntry `json:"alerts"`
+}
+
+// cacheEntry is the cached result of a single prometheus server.
+type cacheEntry struct {
+	// Updated is the time of the last successful request.
+	Updated time.Time `json:"updated"`
+	// Error is the error of the last request, if it failed.
+	Error   string            `json:"error,omitempty"`
+	Targets []v1.ActiveTarget `json:"targets,omitempty"`
+	Alerts  []v1.Alert        `json:"alerts,omitempty"`
+}
+
+// NewCache creates a new cache. Cached results are served for the grace period
+// after the last successful request. If path is not empty, the cache is saved
+// to that file at most every cacheSaveInterval.
+func NewCache(path string, grace time.Duration) *Cache {
+	return &Cache{
+		path:  path,
+		grace: grace,
+		data: cacheData{
+			Targets: map[string]*cacheEntry{},
+			Alerts:  map[string]*cacheEntry{},
+		},
+	}
+}
+
+// LoadCache loads a cache saved by a server.
+func LoadCache(path string) (*Cache, error) {
+	b, err := ioutil.ReadFile(path) //nolint:gosec // the path is configured by the user
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCache("", 0)
+	if err := json.Unmarshal(b, &c.data); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// targets updates the cache with the result of a successful request. If the request
+// failed, the cached result and its age is returned if it is within the grace period.
+func (c *Cache) targets(server string, r v1.TargetsResult, err error) (v1.TargetsResult, time.Duration) {
+	if c == nil {
+		return r, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.update(c.data.Targets, server, err)
+
+	if err == nil {
+		e.Targets = cloneTargets(r.Active)
+
+		return r, 0
+	}
+
+	age := time.Since(e.Updated)
+	if e.Targets == nil || age > c.grace {
+		return r, 0
+	}
+
+	return v1.TargetsResult{Active: cloneTargets(e.Targets)}, age
+}
+
+// alerts updates the cache with the result of a successful request. If the request
+// failed, the cached result and its age is returned if it is within the grace period.
+func (c *Cache) alerts(server string, r v1.AlertsResult, err error) (v1.AlertsResult, time.Duration) {
+	if c == nil {
+		return r, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.update(c.data.Alerts, server, err)
+
+	if err == nil {
+		e.Alerts = cloneAlerts(r.Alerts)
+		if e.Alerts == nil {
+			e.Alerts = []v1.Alert{}
+		}
+
+		return r, 0
+	}
+
+	age := time.Since(e.Updated)
+	if e.Alerts == nil || age > c.grace {
+		return r, 0
+	}
+
+	return v1.AlertsResult{Alerts: cloneAlerts(e.Alerts)}, age
+}
+
+// update records the result of a request. It returns the entry of the server.
+func (c *Cache) update(entries map[string]*cacheEntry, server string, err error) *cacheEntry {
+	e, ok := entries[server]
+	if !ok {
+		e = &cacheEntry{}
+		entries[server] = e
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Error = ""
+		e.Updated = time.Now()
+	}
+
+	return e
+}
+
+// save writes the cache to the file, if it was not written within the
+// cacheSaveInterval. Errors are ignored, the cache file is only a convenience for
+// the CLI.
+func (c *Cache) save() {
+	if c == nil || c.path == "" {
+		return
+	}
+
+	c.mu.Lock()
+	recent := time.Since(c.saved) < cacheSaveInterval
+	c.mu.Unlock()
+
+	if recent {
+		return
+	}
+
+	_ = c.Save()
+}
+
+// Save writes the cache atomically to the file. It must be called before the process
+// exits, the results of the last cacheSaveInterval are not saved otherwise.
+func (c *Cache) Save() error {
+	if c == nil || c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.saved = time.Now()
+	b, err := json.Marshal(c.data)
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), c.path)
+}
+
+// targetResults returns the cached targets of all servers. Results of servers whose last
+// request failed are stale.
+func (c *Cache) targetResults(appendScraperAsTarget bool) []result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []result{}
+
+	for _, server := range servers(c.data.Targets) {
+		e := c.data.Targets[server]
+		r := result{
+			server: server,
+			target: v1.TargetsResult{Active: cloneTargets(e.Targets)},
+			age:    e.age(),
+		}
+
+		if appendScraperAsTarget {
+			var err error
+			if e.Error != "" {
+				err = cachedError(e.Error)
+			}
+
+			r.target.Active = append(r.target.Active, scraperTarget(server, e.Updated, 0, err))
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// alertResults returns the cached alerts of all servers. Results of servers whose last
+// request failed are stale.
+func (c *Cache) alertResults() []alertResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []alertResult{}
+
+	for _, server := range servers(c.data.Alerts) {
+		e := c.data.Alerts[server]
+		results = append(results, alertResult{
+			server: server,
+			alert:  v1.AlertsResult{Alerts: cloneAlerts(e.Alerts)},
+			age:    e.age(),
+		})
+	}
+
+	return results
+}
+
+// age returns the age of the entry if the last request failed.
+func (e *cacheEntry) age() time.Duration {
+	if e.Error == "" {
+		return 0
+	}
+
+	return time.Since(e.Updated)
+}
+
+type cachedError string
+
+func (e cachedError) Error() string {
+	return string(e)
+}
+
+// markStale adds the stale labels with the age to the label set.
+func markStale(ls model.LabelSet, age time.Duration) {
+	ls[staleLabelName] = "true"
+	ls[staleAgeLabelName] = model.LabelValue(age.Round(time.Second).String())
+}
+
+// unmarkStale removes the stale labels from the label set.
+func unmarkStale(ls model.LabelSet) {
+	delete(ls, staleLabelName)
+	delete(ls, staleAgeLabelName)
+}
+
+// staleness returns the suffix of the table output of stale results.
+func staleness(ls model.LabelSet) string {
+	if ls[staleLabelName] != "true" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (stale %s)", ls[staleAgeLabelName])
+}
+
+func servers(entries map[string]*cacheEntry) []string {
+	s := make([]string, 0, len(entries))
+	for server := range entries {
+		s = append(s, server)
+	}
+
+	sort.Strings(s)
+
+	return s
+}
+
+func cloneTargets(targets []v1.ActiveTarget) []v1.ActiveTarget {
+	if targets == nil {
+		return nil
+	}
+
+	c := make([]v1.ActiveTarget, 0, len(targets))
+
+	for i := range targets {
+		t := targets[i]
+		t.Labels = t.Labels.Clone()
+
+		discovered := make(map[string]string, len(t.DiscoveredLabels))
+		for k, v := range t.DiscoveredLabels {
+			discovered[k] = v
+		}
+
+		t.DiscoveredLabels = discovered
+		c = append(c, t)
+	}
+
+	return c
+}
+
+func cloneAlerts(alerts []v1.Alert) []v1.Alert {
+	if alerts == nil {
+		return nil
+	}
+
+	c := make([]v1.Alert, 0, len(alerts))
+
+	for _, a := range alerts {
+		a.Labels = a.Labels.Clone()
+		a.Annotations = a.Annotations.Clone()
+		c = append(c, a)
+	}
+
+	return c
+}
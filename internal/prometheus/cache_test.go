@@ -0,0 +1,150 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	var failing int32
+
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			fmt.Fprintln(w, targets1)
+		case "/api/v1/alerts":
+			fmt.Fprintln(w, alerts1)
+		}
+	}))
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			fmt.Fprintln(w, targets2)
+		case "/api/v1/alerts":
+			fmt.Fprintln(w, alerts2)
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := NewCache(path, time.Hour)
+
+	cli, err := New([]string{s1.URL, s2.URL}, WithCache(cache), WithRetries(0, 0))
+	require.NoError(t, err)
+
+	fresh, _, err := cli.Targets(context.Background(), false)
+	require.NoError(t, err)
+	freshAlerts, _, err := cli.Alerts(context.Background())
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&failing, 1)
+
+	server := "127.0.0.1:" + port(t, s1.URL)
+	expected := len(fresh.Filter(TargetByServer(regexp.MustCompile(regexp.QuoteMeta(server)))))
+
+	t.Run("the targets of the failing server must be stale", func(t *testing.T) {
+		targets, _, err := cli.Targets(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, targets, len(fresh))
+
+		stale := 0
+
+		for _, target := range targets {
+			if target.Labels[staleLabelName] == "true" {
+				stale++
+
+				assert.Equal(t, server, string(target.Labels[sourceLabelName]))
+				assert.NotEmpty(t, target.Labels[staleAgeLabelName])
+				assert.Contains(t, target.Row()[6], "stale")
+			}
+		}
+
+		assert.Equal(t, expected, stale)
+
+		assert.Empty(t, DiffTargets(fresh, targets), "stale targets must not be removed and added again")
+	})
+
+	t.Run("the alerts of the failing server must be stale", func(t *testing.T) {
+		alerts, warnings, err := cli.Alerts(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+		require.Len(t, alerts, len(freshAlerts))
+
+		for _, a := range alerts {
+			assert.Equal(t, string(a.Labels[sourceLabelName]) == server, a.Annotations[staleLabelName] == "true")
+			assert.NotContains(t, a.Labels, staleLabelName)
+		}
+
+		assert.Empty(t, DiffAlerts(freshAlerts, alerts), "stale alerts must not be resolved and firing again")
+
+		assert.Len(t, append(freshAlerts, alerts...).Deduplicate(), len(freshAlerts), "stale alerts must be deduplicated with fresh alerts")
+
+		for _, a := range append(freshAlerts, alerts...).Deduplicate() {
+			assert.NotContains(t, a.Annotations, staleLabelName, "an alert with a fresh source must not be stale")
+		}
+	})
+
+	t.Run("the cache file must contain the stale results", func(t *testing.T) {
+		cache.mu.Lock()
+		cache.saved = time.Time{}
+		cache.mu.Unlock()
+
+		_, _, err := cli.Targets(context.Background(), false)
+		require.NoError(t, err)
+
+		c, err := LoadCache(path)
+		require.NoError(t, err)
+
+		cached, err := New(nil, WithCacheOnly(c))
+		require.NoError(t, err)
+
+		targets, _, err := cached.Targets(context.Background(), true)
+		require.NoError(t, err)
+		assert.Len(t, targets, len(fresh)+2)
+	})
+
+	t.Run("the cache file must not be written on every request", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+
+		_, _, err := cli.Targets(context.Background(), false)
+		require.NoError(t, err)
+		assert.NoFileExists(t, path)
+
+		require.NoError(t, cache.Save())
+		assert.FileExists(t, path)
+	})
+
+	t.Run("no stale results must be served after the grace period", func(t *testing.T) {
+		cache.grace = 0
+
+		targets, warnings, err := cli.Targets(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], server)
+		assert.Len(t, targets, len(fresh)-expected)
+
+		alerts, warnings, err := cli.Alerts(context.Background())
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], server)
+
+		for _, a := range alerts {
+			assert.NotEqual(t, server, string(a.Labels[sourceLabelName]))
+		}
+	})
+}
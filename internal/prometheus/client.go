@@ -2,7 +2,12 @@
 package prometheus
 
 import (
+	"context"
+	"net/http"
 	"net/url"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -10,31 +15,207 @@ import (
 
 // Client is a prometheus API client·
 type Client struct {
-	clients map[string]v1.API
+	mu            sync.RWMutex
+	clients       map[string]v1.API
+	urls          map[string]string
+	serverTimeout time.Duration
+	limit         chan struct{}
+	retries       int
+	backoff       time.Duration
+	threshold     int
+	cooldown      time.Duration
+	cache         *Cache
+	cacheOnly     bool
+}
+
+// Option is a functional option to configure the client.
+type Option func(*Client)
+
+// WithServerTimeout sets the timeout for requests to a single prometheus server.
+// It is independent of the deadline of the overall request, so that a slow server
+// does not affect the results of the others. A zero duration means no timeout.
+func WithServerTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.serverTimeout = d
+	}
+}
+
+// WithMaxInFlight limits the number of concurrent requests to all prometheus
+// servers. A zero value means no limit.
+func WithMaxInFlight(n int) Option {
+	return func(c *Client) {
+		c.limit = nil
+
+		if n > 0 {
+			c.limit = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithRetries retries idempotent requests on transient errors (connection refused
+// or reset, 502, 503 and 504) up to n times with an exponential backoff with jitter.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithCircuitBreaker skips the requests to a prometheus server for the cooldown duration
+// after threshold consecutive failures. A zero threshold disables the circuit breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.threshold = threshold
+		c.cooldown = cooldown
+	}
+}
+
+// WithCache serves the cached results of failing prometheus servers.
+func WithCache(c *Cache) Option {
+	return func(cli *Client) {
+		cli.cache = c
+	}
+}
+
+// WithCacheOnly returns the results of the cache only without any requests to the
+// prometheus servers. The results of servers whose last request failed are stale.
+func WithCacheOnly(c *Cache) Option {
+	return func(cli *Client) {
+		cli.cache = c
+		cli.cacheOnly = true
+	}
 }
 
 // New creates a new client.
-func New(urls ...string) (*Client, error) {
+func New(urls []string, opts ...Option) (*Client, error) {
 	client := Client{
 		clients: map[string]v1.API{},
+		urls:    map[string]string{},
+	}
+
+	for _, opt := range opts {
+		opt(&client)
 	}
 
+	if err := client.SetURLs(urls...); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// SetURLs replaces the prometheus servers of the client. The API clients of
+// unchanged servers are reused.
+func (c *Client) SetURLs(urls ...string) error {
+	c.mu.RLock()
+	current, currentURLs := c.clients, c.urls
+	c.mu.RUnlock()
+
+	clients := make(map[string]v1.API, len(urls))
+	serverURLs := make(map[string]string, len(urls))
+
 	for _, u := range urls {
 		parsed, err := url.Parse(u)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		c, err := api.NewClient(api.Config{
-			Address: u,
+		server := parsed.Hostname() + ":" + parsed.Port()
+
+		if cli, ok := current[server]; ok && currentURLs[server] == u {
+			clients[server] = cli
+			serverURLs[server] = u
+
+			continue
+		}
+
+		cli, err := api.NewClient(api.Config{
+			Address:      u,
+			RoundTripper: c.transport(),
 		})
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		client.clients[parsed.Hostname()+":"+parsed.Port()] = v1.NewAPI(c)
+		clients[server] = v1.NewAPI(cli)
+		serverURLs[server] = u
 	}
 
-	return &client, nil
+	c.mu.Lock()
+	c.clients, c.urls = clients, serverURLs
+	c.mu.Unlock()
+
+	return nil
+}
+
+// transport returns a new transport for a prometheus server.
+func (c *Client) transport() http.RoundTripper {
+	return &transport{
+		next:    api.DefaultRoundTripper,
+		limit:   c.limit,
+		retries: c.retries,
+		backoff: c.backoff,
+		breaker: &breaker{
+			threshold: c.threshold,
+			cooldown:  c.cooldown,
+		},
+	}
+}
+
+// URLs returns the sorted URLs of the prometheus servers.
+func (c *Client) URLs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	urls := make([]string, 0, len(c.urls))
+	for _, u := range c.urls {
+		urls = append(urls, u)
+	}
+
+	sort.Strings(urls)
+
+	return urls
+}
+
+// ServerNames returns the sorted names (host:port) of the prometheus servers.
+func (c *Client) ServerNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.urls))
+	for name := range c.urls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ServerURL returns the URL of a prometheus server by its name (host:port) or an empty
+// string if the server is unknown.
+func (c *Client) ServerURL(server string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.urls[server]
+}
+
+// apis returns the current API clients by server name. The returned map is
+// replaced and never modified by SetURLs, so it must not be modified either.
+func (c *Client) apis() map[string]v1.API {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.clients
+}
+
+// serverContext returns the context for a request to a single prometheus server.
+func (c *Client) serverContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.serverTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.serverTimeout)
 }
@@ -8,7 +8,9 @@ import (
 	"net/url"
 	"regexp"
 	"testing"
+	"time"
 
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,9 +23,9 @@ func TestTarget(t *testing.T) {
 		fmt.Fprintln(w, targets2)
 	}))
 
-	cli, err := New(s1.URL, s2.URL)
+	cli, err := New([]string{s1.URL, s2.URL})
 	require.NoError(t, err)
-	targets, err := cli.Targets(context.Background(), false)
+	targets, _, err := cli.Targets(context.Background(), false)
 	require.NoError(t, err)
 
 	t.Run("the number of targets must be 4", func(t *testing.T) {
@@ -50,6 +52,76 @@ func TestTarget(t *testing.T) {
 		targets = targets.Filter(filters...)
 		assert.Len(t, targets, 1)
 	})
+
+	t.Run("a failing server must be returned as warning", func(t *testing.T) {
+		s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer s3.Close()
+
+		c, err := New([]string{s1.URL, s3.URL}, WithRetries(0, 0))
+		require.NoError(t, err)
+
+		targets, warnings, err := c.Targets(context.Background(), false)
+		require.NoError(t, err)
+		assert.Len(t, targets, 3)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], port(t, s3.URL))
+
+		targets, warnings, err = c.Targets(context.Background(), true)
+		require.NoError(t, err)
+		assert.Len(t, targets, 5, "the scraper targets must be added")
+		assert.Len(t, warnings, 1)
+
+		c, err = New([]string{s3.URL}, WithRetries(0, 0))
+		require.NoError(t, err)
+
+		_, _, err = c.Targets(context.Background(), false)
+		assert.Error(t, err, "an error must be returned if all servers fail")
+	})
+}
+
+func TestServerTimeout(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, targets1)
+	}))
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	cli, err := New([]string{s1.URL, s2.URL}, WithServerTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	targets, _, err := cli.Targets(ctx, true)
+	require.NoError(t, err)
+
+	t.Run("the slow server must be reported as down", func(t *testing.T) {
+		scrapers := 0
+
+		for _, s := range targets {
+			if s.ScrapePool != sourcesJobName {
+				continue
+			}
+
+			scrapers++
+
+			expected := v1.HealthGood
+			if s.ScrapeURL == "127.0.0.1:"+port(t, s2.URL) {
+				expected = v1.HealthBad
+			}
+
+			assert.Equal(t, expected, s.Health, s.ScrapeURL)
+		}
+
+		assert.Equal(t, 2, scrapers)
+	})
+
+	t.Run("the targets of the other server must be returned", func(t *testing.T) {
+		assert.Len(t, targets, 5)
+	})
 }
 
 func TestAlert(t *testing.T) {
@@ -60,10 +132,11 @@ func TestAlert(t *testing.T) {
 		fmt.Fprintln(w, alerts2)
 	}))
 
-	cli, err := New(s1.URL, s2.URL)
+	cli, err := New([]string{s1.URL, s2.URL})
 	require.NoError(t, err)
-	alerts, err := cli.Alerts(context.Background())
+	alerts, warnings, err := cli.Alerts(context.Background())
 	require.NoError(t, err)
+	assert.Empty(t, warnings)
 
 	t.Run("the number of alerts must be 4", func(t *testing.T) {
 		assert.Len(t, alerts, 2)
@@ -79,6 +152,28 @@ func TestAlert(t *testing.T) {
 		assert.Len(t, m, 2)
 	})
 
+	t.Run("a failing server must be returned as warning", func(t *testing.T) {
+		s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer s3.Close()
+
+		c, err := New([]string{s1.URL, s3.URL}, WithRetries(0, 0))
+		require.NoError(t, err)
+
+		alerts, warnings, err := c.Alerts(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, alerts, 1)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], port(t, s3.URL))
+
+		c, err = New([]string{s3.URL}, WithRetries(0, 0))
+		require.NoError(t, err)
+
+		_, _, err = c.Alerts(context.Background())
+		assert.Error(t, err, "an error must be returned if all servers fail")
+	})
+
 	t.Run("the filtered alerts must contain exactly one target", func(t *testing.T) {
 		filters := []AlertFilterFunc{
 			AlertByState("firing"),
@@ -0,0 +1,193 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v2"
+)
+
+// Configs is a slice of prometheus server configurations.
+type Configs []Config
+
+// Config is the loaded configuration of a prometheus server.
+type Config struct {
+	Server string `json:"server"`
+	YAML   string `json:"yaml"`
+}
+
+// Normalize returns the configurations with sorted keys and uniform formatting. If
+// ignoreExternalLabels is true, the external labels are removed, otherwise only the
+// external labels in ignoreLabels (e.g. the replica label).
+func (c Configs) Normalize(ignoreExternalLabels bool, ignoreLabels ...string) (Configs, error) {
+	configs := make(Configs, 0, len(c))
+
+	for _, cfg := range c {
+		m := map[string]interface{}{}
+
+		if err := yaml.Unmarshal([]byte(cfg.YAML), &m); err != nil {
+			return nil, fmt.Errorf("failed to parse config of %s: %w", cfg.Server, err)
+		}
+
+		if global, ok := m["global"].(map[interface{}]interface{}); ok {
+			if ignoreExternalLabels {
+				delete(global, "external_labels")
+			}
+
+			if labels, ok := global["external_labels"].(map[interface{}]interface{}); ok {
+				for _, l := range ignoreLabels {
+					delete(labels, l)
+				}
+
+				if len(labels) == 0 {
+					delete(global, "external_labels")
+				}
+			}
+		}
+
+		b, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, Config{Server: cfg.Server, YAML: string(b)})
+	}
+
+	return configs, nil
+}
+
+// Diff writes the unified diffs of all configurations to the first configuration.
+func (c Configs) Diff(w io.Writer) error {
+	for i := 1; i < len(c); i++ {
+		err := difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+			A:        splitLines(c[0].YAML),
+			B:        splitLines(c[i].YAML),
+			FromFile: c[0].Server,
+			ToFile:   c[i].Server,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitLines splits s into lines with line endings. Unlike difflib.SplitLines, it does
+// not add an empty line if s ends with a newline.
+func splitLines(s string) []string {
+	return difflib.SplitLines(strings.TrimSuffix(s, "\n"))
+}
+
+// ConfigGroups is a slice of groups of servers with identical configuration.
+type ConfigGroups []ConfigGroup
+
+// ConfigGroup is a group of servers with identical configuration.
+type ConfigGroup struct {
+	Group   int      `json:"group"`
+	Hash    string   `json:"hash"`
+	Servers []string `json:"servers"`
+}
+
+// Header represents a config group header.
+func (g ConfigGroup) Header() []string {
+	return []string{"GROUP", "HASH", "SERVERS"}
+}
+
+// Row represents a config group row.
+func (g ConfigGroup) Row() []string {
+	return []string{strconv.Itoa(g.Group), g.Hash, strings.Join(g.Servers, ",")}
+}
+
+// Groups groups the servers with identical configuration. The groups are sorted by
+// size, the largest group first. The hash is the short sha256 sum of the configuration.
+func (c Configs) Groups() ConfigGroups {
+	byHash := map[string]*ConfigGroup{}
+	groups := ConfigGroups{}
+
+	for _, cfg := range c {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(cfg.YAML)))[:12]
+
+		g, ok := byHash[hash]
+		if !ok {
+			g = &ConfigGroup{Hash: hash}
+			byHash[hash] = g
+		}
+
+		g.Servers = append(g.Servers, cfg.Server)
+	}
+
+	for _, g := range byHash {
+		sort.Strings(g.Servers)
+		groups = append(groups, *g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Servers) != len(groups[j].Servers) {
+			return len(groups[i].Servers) > len(groups[j].Servers)
+		}
+
+		return groups[i].Servers[0] < groups[j].Servers[0]
+	})
+
+	for i := range groups {
+		groups[i].Group = i + 1
+	}
+
+	return groups
+}
+
+// Sort sorts configurations by server.
+func (c Configs) Sort() {
+	sort.SliceStable(c, func(i, j int) bool {
+		return c[i].Server < c[j].Server
+	})
+}
+
+// Configs returns the configurations of all prometheus servers sorted by server.
+func (c *Client) Configs(ctx context.Context) (Configs, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	clients := c.apis()
+	results := make(chan Config, len(clients))
+
+	for server, client := range clients {
+		server, client := server, client
+
+		g.Go(func() error {
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			r, err := client.Config(serverCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get config of %s: %w", server, err)
+			}
+
+			results <- Config{Server: server, YAML: r.YAML}
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	close(results)
+
+	configs := Configs{}
+	for r := range results {
+		configs = append(configs, r)
+	}
+
+	configs.Sort()
+
+	return configs, nil
+}
@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigs(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"success","data":{"yaml":"global:\n  scrape_interval: 15s\n"}}`)
+	}))
+	defer s1.Close()
+
+	cli, err := New([]string{s1.URL})
+	require.NoError(t, err)
+
+	configs, err := cli.Configs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "127.0.0.1:"+port(t, s1.URL), configs[0].Server)
+	assert.Equal(t, "global:\n  scrape_interval: 15s\n", configs[0].YAML)
+}
+
+func TestConfigsNormalize(t *testing.T) {
+	configs := Configs{
+		{Server: "prom1", YAML: "scrape_configs: []\nglobal:\n  external_labels:\n    replica: a\n    cluster: prod\n  scrape_interval: 15s\n"},
+		{Server: "prom2", YAML: "global:\n    scrape_interval: 15s\n    external_labels: {cluster: prod, replica: b}\nscrape_configs: []\n"},
+		{Server: "prom3", YAML: "global:\n  scrape_interval: 1m\n  external_labels:\n    replica: c\nscrape_configs: []\n"},
+	}
+
+	t.Run("identical configs with different replica labels must be grouped", func(t *testing.T) {
+		normalized, err := configs.Normalize(false, "replica")
+		require.NoError(t, err)
+		assert.Equal(t, "global:\n  external_labels:\n    cluster: prod\n  scrape_interval: 15s\nscrape_configs: []\n", normalized[0].YAML)
+		assert.Equal(t, normalized[0].YAML, normalized[1].YAML)
+
+		groups := normalized.Groups()
+		require.Len(t, groups, 2)
+		assert.Equal(t, 1, groups[0].Group)
+		assert.Equal(t, []string{"prom1", "prom2"}, groups[0].Servers)
+		assert.Equal(t, []string{"prom3"}, groups[1].Servers)
+	})
+
+	t.Run("all external labels must be removed", func(t *testing.T) {
+		normalized, err := configs.Normalize(true)
+		require.NoError(t, err)
+		assert.Equal(t, "global:\n  scrape_interval: 1m\nscrape_configs: []\n", normalized[2].YAML)
+		assert.Len(t, normalized.Groups(), 2)
+	})
+
+	t.Run("without ignored labels all configs must differ", func(t *testing.T) {
+		normalized, err := configs.Normalize(false)
+		require.NoError(t, err)
+		assert.Len(t, normalized.Groups(), 3)
+	})
+
+	t.Run("the diff must show the changed lines", func(t *testing.T) {
+		normalized, err := configs.Normalize(true)
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, normalized.Diff(buf))
+		assert.Equal(t, "--- prom1\n+++ prom3\n@@ -1,3 +1,3 @@\n global:\n-  scrape_interval: 15s\n+  scrape_interval: 1m\n scrape_configs: []\n", buf.String())
+	})
+
+	t.Run("an invalid config must return an error", func(t *testing.T) {
+		_, err := Configs{{Server: "prom1", YAML: "global: ["}}.Normalize(false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,203 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const healthConflictLabelName = "promi_health_conflict"
+
+// DedupPolicy selects the target of identical targets with different health status.
+type DedupPolicy string
+
+// The deduplication policies.
+const (
+	// DedupWorstHealth selects the target with the worst health.
+	DedupWorstHealth DedupPolicy = "worst-health"
+	// DedupBestHealth selects the target with the best health.
+	DedupBestHealth DedupPolicy = "best-health"
+	// DedupMajority selects the target with the most common health. On a tie the
+	// worst health wins.
+	DedupMajority DedupPolicy = "majority"
+	// DedupMostRecentScrape selects the target with the most recent scrape.
+	DedupMostRecentScrape DedupPolicy = "most-recent-scrape"
+)
+
+// DedupOption is a functional option to configure the deduplication.
+type DedupOption func(*dedupConfig)
+
+type dedupConfig struct {
+	policy DedupPolicy
+	labels []model.LabelName
+}
+
+// WithDedupPolicy sets the policy to select the target if identical targets have
+// different health status. The default policy is DedupWorstHealth.
+func WithDedupPolicy(p DedupPolicy) DedupOption {
+	return func(c *dedupConfig) {
+		c.policy = p
+	}
+}
+
+// WithDedupLabels adds labels to the scrape url to identify identical targets.
+func WithDedupLabels(labels ...string) DedupOption {
+	return func(c *dedupConfig) {
+		for _, l := range labels {
+			c.labels = append(c.labels, model.LabelName(l))
+		}
+	}
+}
+
+// Deduplicate deduplicates targets by scrape url and the configured labels. If identical
+// targets have different health status, the target is selected by the configured policy
+// and the health of all sources is recorded in the healthConflictLabelName label.
+// Multiple sources are appended in the sourceLabelName label. The targets are not modified.
+func (t Targets) Deduplicate(opts ...DedupOption) Targets {
+	cfg := dedupConfig{
+		policy: DedupWorstHealth,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	index := map[string]int{}
+	groups := []Targets{}
+
+	for i := range t {
+		k := cfg.key(&t[i])
+
+		j, ok := index[k]
+		if !ok {
+			index[k] = len(groups)
+			groups = append(groups, Targets{})
+			j = index[k]
+		}
+
+		groups[j] = append(groups[j], t[i])
+	}
+
+	targets := make(Targets, 0, len(groups))
+
+	for _, g := range groups {
+		targets = append(targets, g.merge(cfg.policy))
+	}
+
+	return targets
+}
+
+func (c dedupConfig) key(t *Target) string {
+	var b strings.Builder
+
+	b.WriteString(t.ScrapeURL)
+
+	for _, l := range c.labels {
+		b.WriteString("\xff")
+		b.WriteString(string(l))
+		b.WriteString("=")
+		b.WriteString(string(t.Labels[l]))
+	}
+
+	return b.String()
+}
+
+// merge merges identical targets to one target.
+func (t Targets) merge(policy DedupPolicy) Target {
+	// sort by source to get a deterministic result
+	sort.SliceStable(t, func(i, j int) bool {
+		return t[i].getSource() < t[j].getSource()
+	})
+
+	selected := t[0]
+	sources := make([]string, 0, len(t))
+	conflict := false
+
+	for i := range t {
+		sources = append(sources, t[i].getSource())
+		conflict = conflict || t[i].Health != selected.Health
+	}
+
+	switch policy {
+	case DedupBestHealth:
+		for i := range t {
+			if healthRank(t[i].Health) > healthRank(selected.Health) {
+				selected = t[i]
+			}
+		}
+	case DedupMajority:
+		selected = t.majority()
+	case DedupMostRecentScrape:
+		for i := range t {
+			if t[i].LastScrape.After(selected.LastScrape) {
+				selected = t[i]
+			}
+		}
+	default:
+		for i := range t {
+			if healthRank(t[i].Health) < healthRank(selected.Health) {
+				selected = t[i]
+			}
+		}
+	}
+
+	selected.Labels = selected.Labels.Clone()
+
+	// the merged target is stale only if all targets are stale
+	for i := range t {
+		if staleness(t[i].Labels) == "" {
+			unmarkStale(selected.Labels)
+			break
+		}
+	}
+
+	if len(t) > 1 {
+		selected.Labels[sourceLabelName] = model.LabelValue(joinSources(sources...))
+	}
+
+	if conflict {
+		health := make([]string, 0, len(t))
+		for i := range t {
+			health = append(health, fmt.Sprintf("%s=%s", t[i].getSource(), t[i].Health))
+		}
+
+		selected.Labels[healthConflictLabelName] = model.LabelValue(strings.Join(health, ","))
+	}
+
+	return selected
+}
+
+// majority returns the first target with the most common health. On a tie the
+// target with the worst health is returned.
+func (t Targets) majority() Target {
+	counts := map[v1.HealthStatus]int{}
+	for i := range t {
+		counts[t[i].Health]++
+	}
+
+	selected := t[0]
+
+	for i := range t {
+		c, s := counts[t[i].Health], counts[selected.Health]
+		if c > s || (c == s && healthRank(t[i].Health) < healthRank(selected.Health)) {
+			selected = t[i]
+		}
+	}
+
+	return selected
+}
+
+// healthRank ranks the health status from worst to best.
+func healthRank(h v1.HealthStatus) int {
+	switch h {
+	case v1.HealthBad:
+		return 0
+	case v1.HealthUnknown:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,212 @@
+package prometheus
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// EventType is the type of a change event.
+type EventType string
+
+// The event types.
+const (
+	EventTargetAdded         EventType = "target_added"
+	EventTargetRemoved       EventType = "target_removed"
+	EventTargetHealthChanged EventType = "target_health_changed"
+	EventAlertPending        EventType = "alert_pending"
+	EventAlertFiring         EventType = "alert_firing"
+	EventAlertResolved       EventType = "alert_resolved"
+)
+
+// Events is a slice of events.
+type Events []Event
+
+// Event is a change of a target or an alert between two snapshots.
+type Event struct {
+	Type   EventType      `json:"type"`
+	Time   time.Time      `json:"time"`
+	Server string         `json:"server"`
+	Labels model.LabelSet `json:"labels"`
+	// Previous is the previous health of a target or the previous state of an alert.
+	Previous string  `json:"previous,omitempty"`
+	Target   *Target `json:"target,omitempty"`
+	Alert    *Alert  `json:"alert,omitempty"`
+}
+
+// Job returns the job label value.
+func (e Event) Job() string {
+	job, ok := e.Labels[jobLabelName]
+	if !ok {
+		job = dlftJobName
+	}
+
+	return string(job)
+}
+
+// DiffTargets returns the events between the previous and the current targets.
+func DiffTargets(previous, current Targets) Events {
+	now := time.Now()
+	events := Events{}
+
+	prev := make(map[string]Target, len(previous))
+	for i := range previous {
+		prev[previous[i].Key()] = previous[i]
+	}
+
+	cur := make(map[string]Target, len(current))
+	for i := range current {
+		cur[current[i].Key()] = current[i]
+	}
+
+	for k := range cur {
+		t := cur[k]
+
+		old, ok := prev[k]
+
+		switch {
+		case !ok:
+			events = append(events, t.event(EventTargetAdded, now, ""))
+		case old.Health != t.Health:
+			events = append(events, t.event(EventTargetHealthChanged, now, string(old.Health)))
+		}
+	}
+
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			t := prev[k]
+			events = append(events, t.event(EventTargetRemoved, now, string(t.Health)))
+		}
+	}
+
+	events.sort()
+
+	return events
+}
+
+// DiffAlerts returns the events between the previous and the current alerts.
+func DiffAlerts(previous, current Alerts) Events {
+	now := time.Now()
+	events := Events{}
+
+	prev := make(map[model.Fingerprint]Alert, len(previous))
+	for i := range previous {
+		prev[previous[i].Labels.Fingerprint()] = previous[i]
+	}
+
+	cur := make(map[model.Fingerprint]Alert, len(current))
+	for i := range current {
+		cur[current[i].Labels.Fingerprint()] = current[i]
+	}
+
+	for k := range cur {
+		a := cur[k]
+
+		old, ok := prev[k]
+		if ok && old.State == a.State {
+			continue
+		}
+
+		previousState := ""
+		if ok {
+			previousState = string(old.State)
+		}
+
+		switch a.State {
+		case v1.AlertStatePending:
+			events = append(events, a.event(EventAlertPending, now, previousState))
+		case v1.AlertStateFiring:
+			events = append(events, a.event(EventAlertFiring, now, previousState))
+		}
+	}
+
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			a := prev[k]
+			events = append(events, a.event(EventAlertResolved, now, string(a.State)))
+		}
+	}
+
+	events.sort()
+
+	return events
+}
+
+// EventFilterFunc is a function to filter events. If function returns true
+// event is selected else omitted.
+type EventFilterFunc func(Event) bool
+
+// Filter filters Events with EventFilterFunc.
+func (e Events) Filter(filters ...EventFilterFunc) Events {
+	events := Events{}
+
+	for i := range e {
+		selectEvent := true
+		for _, f := range filters {
+			selectEvent = selectEvent && f(e[i])
+		}
+
+		if selectEvent {
+			events = append(events, e[i])
+		}
+	}
+
+	return events
+}
+
+// EventByServer filters Events by prometheus server.
+func EventByServer(r *regexp.Regexp) EventFilterFunc {
+	return func(e Event) bool {
+		return r.MatchString(e.Server)
+	}
+}
+
+// EventByJob filters Events by job.
+func EventByJob(r *regexp.Regexp) EventFilterFunc {
+	return func(e Event) bool {
+		return r.MatchString(e.Job())
+	}
+}
+
+// EventBySelector filters Events by Selector.
+func EventBySelector(selector labels.Selector) EventFilterFunc {
+	return func(e Event) bool {
+		return selector.Matches(k8sLabels{LabelSet: e.Labels})
+	}
+}
+
+func (e Events) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].Type != e[j].Type {
+			return e[i].Type < e[j].Type
+		}
+
+		return e[i].Labels.Before(e[j].Labels)
+	})
+}
+
+func (t Target) event(typ EventType, now time.Time, previous string) Event {
+	return Event{
+		Type:     typ,
+		Time:     now,
+		Server:   t.Server(),
+		Labels:   t.Labels.Clone(),
+		Previous: previous,
+		Target:   &t,
+	}
+}
+
+func (a Alert) event(typ EventType, now time.Time, previous string) Event {
+	return Event{
+		Type:     typ,
+		Time:     now,
+		Server:   string(a.Labels[sourceLabelName]),
+		Labels:   a.Labels.Clone(),
+		Previous: previous,
+		Alert:    &a,
+	}
+}
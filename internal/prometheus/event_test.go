@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"regexp"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTargets(t *testing.T) {
+	target := func(url string, health v1.HealthStatus) Target {
+		return Target{
+			ActiveTarget: v1.ActiveTarget{
+				ScrapeURL: url,
+				Health:    health,
+				Labels: model.LabelSet{
+					jobLabelName:    "job1",
+					sourceLabelName: "src1",
+				},
+			},
+		}
+	}
+
+	previous := Targets{
+		target("http://url1.example.com", v1.HealthGood),
+		target("http://url2.example.com", v1.HealthGood),
+	}
+	current := Targets{
+		target("http://url2.example.com", v1.HealthBad),
+		target("http://url3.example.com", v1.HealthGood),
+	}
+
+	events := DiffTargets(previous, current)
+
+	types := []EventType{}
+	for _, e := range events {
+		types = append(types, e.Type)
+		assert.Equal(t, "src1", e.Server)
+	}
+
+	assert.Equal(t, []EventType{EventTargetAdded, EventTargetHealthChanged, EventTargetRemoved}, types)
+	assert.Equal(t, string(v1.HealthGood), events[1].Previous)
+	assert.Empty(t, DiffTargets(current, current))
+}
+
+func TestDiffAlerts(t *testing.T) {
+	alert := func(name string, state v1.AlertState) Alert {
+		return Alert{
+			Alert: v1.Alert{
+				State: state,
+				Labels: model.LabelSet{
+					alertNameLabelName: model.LabelValue(name),
+					jobLabelName:       "job1",
+					sourceLabelName:    "src1",
+				},
+			},
+		}
+	}
+
+	previous := Alerts{
+		alert("alert1", v1.AlertStatePending),
+		alert("alert2", v1.AlertStateFiring),
+	}
+	current := Alerts{
+		alert("alert1", v1.AlertStateFiring),
+		alert("alert3", v1.AlertStatePending),
+	}
+
+	events := DiffAlerts(previous, current)
+
+	types := []EventType{}
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+
+	assert.Equal(t, []EventType{EventAlertFiring, EventAlertPending, EventAlertResolved}, types)
+	assert.Equal(t, string(v1.AlertStatePending), events[0].Previous)
+
+	t.Run("filter events by job and server", func(t *testing.T) {
+		assert.Len(t, events.Filter(EventByJob(regexp.MustCompile("job1")), EventByServer(regexp.MustCompile("src1"))), 3)
+		assert.Empty(t, events.Filter(EventByServer(regexp.MustCompile("src2"))))
+	})
+}
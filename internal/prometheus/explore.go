@@ -0,0 +1,250 @@
+package prometheus
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// Presence is a label name, label value or metric with the prometheus servers it is
+// present on and the servers it is missing on. Servers whose request failed are neither.
+type Presence struct {
+	Name    string   `json:"name"`
+	Servers []string `json:"servers"`
+	Missing []string `json:"missing"`
+}
+
+// Header represents the header of a presence row.
+func (p Presence) Header() []string {
+	return []string{"NAME", "SERVERS", "MISSING_ON"}
+}
+
+// Row represents a presence row.
+func (p Presence) Row() []string {
+	return []string{p.Name, strings.Join(p.Servers, ","), strings.Join(p.Missing, ",")}
+}
+
+// Presences is a list of presences.
+type Presences []Presence
+
+// Missing returns the presences that are missing on at least one server.
+func (p Presences) Missing() Presences {
+	result := Presences{}
+
+	for _, pr := range p {
+		if len(pr.Missing) > 0 {
+			result = append(result, pr)
+		}
+	}
+
+	return result
+}
+
+// newPresences returns the sorted presences of the names present on the servers.
+func newPresences(present map[string]map[string]bool, servers []string) Presences {
+	result := make(Presences, 0, len(present))
+
+	for name, on := range present {
+		p := Presence{Name: name, Servers: []string{}, Missing: []string{}}
+
+		for _, s := range servers {
+			if on[s] {
+				p.Servers = append(p.Servers, s)
+			} else {
+				p.Missing = append(p.Missing, s)
+			}
+		}
+
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// LabelPresences returns the label names of all prometheus servers or the values of the
+// label if it is not empty, with the servers they are present on. The values of the
+// server label are the names of the servers.
+func (c *Client) LabelPresences(ctx context.Context, label string, matches []string, start, end time.Time) (Presences, v1.Warnings, error) {
+	if label == sourceLabelName {
+		servers := c.ServerNames()
+		present := map[string]map[string]bool{}
+
+		for _, s := range servers {
+			present[s] = map[string]bool{s: true}
+		}
+
+		return newPresences(present, servers), nil, nil
+	}
+
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		if label == "" {
+			return client.LabelNames(ctx, matches, start, end)
+		}
+
+		values, w, err := client.LabelValues(ctx, label, matches, start, end)
+
+		names := make([]string, 0, len(values))
+		for _, v := range values {
+			names = append(names, string(v))
+		}
+
+		return names, w, err
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	servers := make([]string, 0, len(results))
+	present := map[string]map[string]bool{}
+
+	for _, r := range results {
+		servers = append(servers, r.server)
+
+		for _, n := range r.value.([]string) {
+			if present[n] == nil {
+				present[n] = map[string]bool{}
+			}
+
+			present[n][r.server] = true
+		}
+	}
+
+	return newPresences(present, servers), warnings, nil
+}
+
+// SeriesCount is the number of series of a metric on every prometheus server.
+type SeriesCount struct {
+	Metric string         `json:"metric"`
+	Counts map[string]int `json:"counts"`
+	// servers are the servers of the columns in table output
+	servers []string
+}
+
+// Header represents the header of a series count row with a column for every server.
+func (s SeriesCount) Header() []string {
+	return append([]string{"METRIC"}, s.servers...)
+}
+
+// Row represents a series count row.
+func (s SeriesCount) Row() []string {
+	row := []string{s.Metric}
+
+	for _, server := range s.servers {
+		row = append(row, strconv.Itoa(s.Counts[server]))
+	}
+
+	return row
+}
+
+// SeriesCounts is a list of series counts.
+type SeriesCounts []SeriesCount
+
+// Presences returns the presences of the metrics.
+func (s SeriesCounts) Presences() Presences {
+	result := make(Presences, 0, len(s))
+
+	for _, c := range s {
+		p := Presence{Name: c.Metric, Servers: []string{}, Missing: []string{}}
+
+		for _, server := range c.servers {
+			if c.Counts[server] > 0 {
+				p.Servers = append(p.Servers, server)
+			} else {
+				p.Missing = append(p.Missing, server)
+			}
+		}
+
+		result = append(result, p)
+	}
+
+	return result
+}
+
+// SeriesCounts returns the number of series matching any of the matchers of all
+// prometheus servers by metric name. The series of every matcher are requested
+// separately, so that a matcher without series on all servers is returned as metric
+// with zero counts. The counts of servers whose request failed are missing.
+func (c *Client) SeriesCounts(ctx context.Context, matches []string, start, end time.Time) (SeriesCounts, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		series := make([][]model.LabelSet, 0, len(matches))
+		warnings := v1.Warnings{}
+
+		for _, m := range matches {
+			s, w, err := client.Series(ctx, []string{m}, start, end)
+			if err != nil {
+				return nil, warnings, err
+			}
+
+			series = append(series, s)
+			warnings = append(warnings, w...)
+		}
+
+		return series, warnings, nil
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	servers := make([]string, 0, len(results))
+	counts := map[string]map[string]int{}
+	matched := make([]bool, len(matches))
+
+	for _, r := range results {
+		servers = append(servers, r.server)
+
+		// a series can match several matchers
+		seen := map[model.Fingerprint]bool{}
+
+		for i, series := range r.value.([][]model.LabelSet) {
+			matched[i] = matched[i] || len(series) > 0
+
+			for _, s := range series {
+				if seen[s.Fingerprint()] {
+					continue
+				}
+
+				seen[s.Fingerprint()] = true
+
+				metric := string(s[model.MetricNameLabel])
+				if counts[metric] == nil {
+					counts[metric] = map[string]int{}
+				}
+
+				counts[metric][r.server]++
+			}
+		}
+	}
+
+	for i, m := range matches {
+		if !matched[i] && counts[m] == nil {
+			counts[m] = map[string]int{}
+		}
+	}
+
+	result := make(SeriesCounts, 0, len(counts))
+
+	for metric, cnt := range counts {
+		for _, s := range servers {
+			if _, ok := cnt[s]; !ok {
+				cnt[s] = 0
+			}
+		}
+
+		result = append(result, SeriesCount{Metric: metric, Counts: cnt, servers: servers})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Metric < result[j].Metric
+	})
+
+	return result, warnings, nil
+}
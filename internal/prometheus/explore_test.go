@@ -0,0 +1,123 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplore(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/labels":
+			fmt.Fprintln(w, `{"status":"success","data":["__name__","job","replica"]}`)
+		case "/api/v1/label/job/values":
+			fmt.Fprintln(w, `{"status":"success","data":["node","web"]}`)
+		case "/api/v1/series":
+			switch r.FormValue("match[]") {
+			case "up":
+				fmt.Fprintln(w, `{"status":"success","data":[{"__name__":"up","job":"node"},{"__name__":"up","job":"web"}]}`)
+			case "node_load1":
+				fmt.Fprintln(w, `{"status":"success","data":[{"__name__":"node_load1","job":"node"}]}`)
+			case "{job=\"node\"}":
+				fmt.Fprintln(w, `{"status":"success","data":[{"__name__":"up","job":"node"},{"__name__":"node_load1","job":"node"}]}`)
+			default:
+				fmt.Fprintln(w, `{"status":"success","data":[]}`)
+			}
+		}
+	}))
+	defer s1.Close()
+
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/labels":
+			fmt.Fprintln(w, `{"status":"success","data":["__name__","job"]}`)
+		case "/api/v1/label/job/values":
+			fmt.Fprintln(w, `{"status":"success","data":["node"]}`)
+		case "/api/v1/series":
+			switch r.FormValue("match[]") {
+			case "up", "{job=\"node\"}":
+				fmt.Fprintln(w, `{"status":"success","data":[{"__name__":"up","job":"node"}]}`)
+			default:
+				fmt.Fprintln(w, `{"status":"success","data":[]}`)
+			}
+		}
+	}))
+	defer s2.Close()
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s3.Close()
+
+	server1, server2 := "127.0.0.1:"+port(t, s1.URL), "127.0.0.1:"+port(t, s2.URL)
+
+	c, err := New([]string{s1.URL, s2.URL, s3.URL}, WithRetries(0, 0))
+	require.NoError(t, err)
+
+	sorted := []string{server1, server2}
+	if server2 < server1 {
+		sorted = []string{server2, server1}
+	}
+
+	t.Run("label names", func(t *testing.T) {
+		presences, warnings, err := c.LabelPresences(context.Background(), "", nil, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+		require.Len(t, warnings, 1, "the failing server must be returned as warning")
+
+		require.Len(t, presences, 3)
+		assert.Equal(t, Presence{Name: "job", Servers: sorted, Missing: []string{}}, presences[1])
+		assert.Equal(t, Presence{Name: "replica", Servers: []string{server1}, Missing: []string{server2}}, presences[2])
+
+		missing := presences.Missing()
+		require.Len(t, missing, 1)
+		assert.Equal(t, "replica", missing[0].Name)
+	})
+
+	t.Run("label values", func(t *testing.T) {
+		presences, _, err := c.LabelPresences(context.Background(), "job", nil, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+
+		missing := presences.Missing()
+		require.Len(t, missing, 1)
+		assert.Equal(t, Presence{Name: "web", Servers: []string{server1}, Missing: []string{server2}}, missing[0])
+	})
+
+	t.Run("series counts", func(t *testing.T) {
+		counts, _, err := c.SeriesCounts(context.Background(), []string{"up", "node_load1"}, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+
+		require.Len(t, counts, 2)
+		assert.Equal(t, "node_load1", counts[0].Metric)
+		assert.Equal(t, map[string]int{server1: 1, server2: 0}, counts[0].Counts)
+		assert.Equal(t, map[string]int{server1: 2, server2: 1}, counts[1].Counts)
+		assert.Equal(t, append([]string{"METRIC"}, sorted...), counts[1].Header())
+
+		missing := counts.Presences().Missing()
+		require.Len(t, missing, 1)
+		assert.Equal(t, Presence{Name: "node_load1", Servers: []string{server1}, Missing: []string{server2}}, missing[0])
+	})
+
+	t.Run("series matching several matchers must be counted once", func(t *testing.T) {
+		counts, _, err := c.SeriesCounts(context.Background(), []string{"up", `{job="node"}`}, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+
+		require.Len(t, counts, 2)
+		assert.Equal(t, map[string]int{server1: 2, server2: 1}, counts[1].Counts)
+	})
+
+	t.Run("a matcher without series must be missing on all servers", func(t *testing.T) {
+		counts, _, err := c.SeriesCounts(context.Background(), []string{"up", "absent_metric"}, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+
+		missing := counts.Presences().Missing()
+		require.Len(t, missing, 1)
+		assert.Equal(t, Presence{Name: "absent_metric", Servers: []string{}, Missing: sorted}, missing[0])
+	})
+}
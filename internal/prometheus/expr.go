@@ -0,0 +1,366 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Expr is a compiled filter expression. It combines comparisons of fields with
+// and, or, not and parentheses, for example:
+//
+//	health=down and (job=~"node.*" or severity="critical") and not server=~"lab.*"
+//
+// The operators are = (equal), != (not equal), =~ (regex match) and !~ (regex does
+// not match). Regular expressions are fully anchored like in PromQL. Fields that are
+// not a property of the target or alert are label names.
+type Expr struct {
+	root exprNode
+}
+
+// exprNode is a node of the syntax tree. It evaluates to true if the item with the
+// field values of get matches.
+type exprNode interface {
+	eval(get func(field string) string) bool
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n andNode) eval(get func(string) string) bool {
+	return n.left.eval(get) && n.right.eval(get)
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n orNode) eval(get func(string) string) bool {
+	return n.left.eval(get) || n.right.eval(get)
+}
+
+type notNode struct {
+	node exprNode
+}
+
+func (n notNode) eval(get func(string) string) bool {
+	return !n.node.eval(get)
+}
+
+type cmpNode struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (n cmpNode) eval(get func(string) string) bool {
+	v := get(n.field)
+
+	switch n.op {
+	case "=":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "=~":
+		return n.re.MatchString(v)
+	default: // !~
+		return !n.re.MatchString(v)
+	}
+}
+
+// ParseExpr parses a filter expression.
+func ParseExpr(s string) (*Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+
+	return &Expr{root: root}, nil
+}
+
+// TargetFilter returns the expression as target filter. The fields are health, job,
+// server, scrape_url, scrape_pool, last_error and the target labels.
+func (e *Expr) TargetFilter() TargetFilterFunc {
+	return func(t Target) bool {
+		return e.root.eval(func(field string) string {
+			switch field {
+			case "health":
+				return string(t.Health)
+			case "job":
+				return t.Job()
+			case "server":
+				return t.Server()
+			case "scrape_url":
+				return t.ScrapeURL
+			case "scrape_pool":
+				return t.ScrapePool
+			case "last_error":
+				return t.LastError
+			}
+
+			return string(t.Labels[model.LabelName(field)])
+		})
+	}
+}
+
+// AlertFilter returns the expression as alert filter. The fields are state, job,
+// server, name and the alert labels.
+func (e *Expr) AlertFilter() AlertFilterFunc {
+	return func(a Alert) bool {
+		return e.root.eval(func(field string) string {
+			switch field {
+			case "state":
+				return string(a.State)
+			case "job":
+				return a.Job()
+			case "server":
+				return a.Server()
+			case "name":
+				return a.Name()
+			}
+
+			return string(a.Labels[model.LabelName(field)])
+		})
+	}
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of expression"
+	}
+
+	return strconv.Quote(t.val)
+}
+
+// lex splits the expression into tokens.
+func lex(s string) ([]token, error) {
+	tokens := []token{}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case strings.HasPrefix(s[i:], "=~"), strings.HasPrefix(s[i:], "!~"), strings.HasPrefix(s[i:], "!="):
+			tokens = append(tokens, token{tokenOp, s[i : i+2], i})
+			i += 2
+		case c == '=':
+			tokens = append(tokens, token{tokenOp, "=", i})
+			i++
+		case c == '\'':
+			// single quoted strings are raw strings
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+
+			tokens = append(tokens, token{tokenString, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+
+			v, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+
+			tokens = append(tokens, token{tokenString, v, i})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n()=!\"'", rune(s[end])) {
+				end++
+			}
+
+			if end == i {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+
+			tokens = append(tokens, token{tokenIdent, s[i:end], i})
+			i = end
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(s)}), nil
+}
+
+// parser is a recursive descent parser of the grammar:
+//
+//	or    = and { "or" and }
+//	and   = unary { "and" unary }
+//	unary = "not" unary | "(" or ")" | field op value
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	if t.typ == tokenIdent && strings.EqualFold(t.val, k) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) or() (exprNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		var right exprNode
+
+		right, err = p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (exprNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		var right exprNode
+
+		right, err = p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (exprNode, error) {
+	if p.keyword("not") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{n}, nil
+	}
+
+	if p.peek().typ == tokenLParen {
+		p.next()
+
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.typ != tokenRParen {
+			return nil, fmt.Errorf("expected \")\" but got %s at position %d", t, t.pos)
+		}
+
+		return n, nil
+	}
+
+	return p.cmp()
+}
+
+func (p *parser) cmp() (exprNode, error) {
+	field := p.next()
+	if field.typ != tokenIdent || !model.LabelName(field.val).IsValid() {
+		return nil, fmt.Errorf("expected field name but got %s at position %d", field, field.pos)
+	}
+
+	op := p.next()
+	if op.typ != tokenOp {
+		return nil, fmt.Errorf("expected operator (=, !=, =~, !~) but got %s at position %d", op, op.pos)
+	}
+
+	value := p.next()
+	if value.typ != tokenString && value.typ != tokenIdent {
+		return nil, fmt.Errorf("expected value but got %s at position %d", value, value.pos)
+	}
+
+	n := cmpNode{field: field.val, op: op.val, value: value.val}
+
+	if op.val == "=~" || op.val == "!~" {
+		re, err := regexp.Compile("^(?:" + value.val + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value.val, err)
+		}
+
+		n.re = re
+	}
+
+	return n, nil
+}
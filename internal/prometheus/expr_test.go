@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExprTargetFilter(t *testing.T) {
+	targets := Targets{
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://node1:9100", Health: v1.HealthBad, Labels: model.LabelSet{jobLabelName: "node", sourceLabelName: "prom1", "severity": "warning"}}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://node2:9100", Health: v1.HealthBad, Labels: model.LabelSet{jobLabelName: "node", sourceLabelName: "lab1", "severity": "warning"}}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://db1:9187", Health: v1.HealthBad, Labels: model.LabelSet{jobLabelName: "postgres", sourceLabelName: "prom1", "severity": "critical"}}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://db2:9187", Health: v1.HealthGood, Labels: model.LabelSet{jobLabelName: "postgres", sourceLabelName: "prom1", "severity": "critical"}}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://web1:8080", Health: v1.HealthBad, Labels: model.LabelSet{jobLabelName: "web", sourceLabelName: "prom1"}, LastError: "connection refused"}},
+	}
+
+	tt := []struct {
+		expr     string
+		expected []string
+	}{
+		{`health=down`, []string{"http://node1:9100", "http://node2:9100", "http://db1:9187", "http://web1:8080"}},
+		{`health=down and (job=~"node.*" or severity="critical") and not server=~"lab.*"`, []string{"http://node1:9100", "http://db1:9187"}},
+		{`job=node or job=postgres and health=up`, []string{"http://node1:9100", "http://node2:9100", "http://db2:9187"}},
+		{`(job=node or job=postgres) and health=up`, []string{"http://db2:9187"}},
+		{`NOT health=down`, []string{"http://db2:9187"}},
+		{`not not health=up`, []string{"http://db2:9187"}},
+		{`job=~"node|web" and server!=prom1`, []string{"http://node2:9100"}},
+		{`scrape_url=~'http://db\d:.*'`, []string{"http://db1:9187", "http://db2:9187"}},
+		{`job=~"pos"`, []string{}},
+		{`job!~"pos"`, []string{"http://node1:9100", "http://node2:9100", "http://db1:9187", "http://db2:9187", "http://web1:8080"}},
+		{`last_error=~"refused"`, []string{}},
+		{`severity!~"warn.*|crit.*"`, []string{"http://web1:8080"}},
+		{`severity=""`, []string{"http://web1:8080"}},
+		{`last_error=~".*refused"`, []string{"http://web1:8080"}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := ParseExpr(tc.expr)
+			require.NoError(t, err)
+
+			urls := []string{}
+			for _, tgt := range targets.Filter(e.TargetFilter()) {
+				urls = append(urls, tgt.ScrapeURL)
+			}
+
+			assert.Equal(t, tc.expected, urls)
+		})
+	}
+}
+
+func TestExprAlertFilter(t *testing.T) {
+	alerts := Alerts{
+		{Alert: v1.Alert{State: v1.AlertStateFiring, Labels: model.LabelSet{alertNameLabelName: "InstanceDown", jobLabelName: "node", sourceLabelName: "prom1"}}},
+		{Alert: v1.Alert{State: v1.AlertStatePending, Labels: model.LabelSet{alertNameLabelName: "InstanceDown", jobLabelName: "web", sourceLabelName: "prom2"}}},
+		{Alert: v1.Alert{State: v1.AlertStateFiring, Labels: model.LabelSet{alertNameLabelName: "DiskFull", jobLabelName: "node", sourceLabelName: "prom2", "severity": "critical"}}},
+	}
+
+	tt := []struct {
+		expr     string
+		expected int
+	}{
+		{`state=firing`, 2},
+		{`name=InstanceDown and not job=node`, 1},
+		{`server=prom2 and (state=pending or severity=critical)`, 2},
+		{`name=~"Instance.*" or severity=critical`, 3},
+	}
+
+	for _, tc := range tt {
+		e, err := ParseExpr(tc.expr)
+		require.NoError(t, err, tc.expr)
+		assert.Len(t, alerts.Filter(e.AlertFilter()), tc.expected, tc.expr)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tt := []string{
+		``,
+		`health`,
+		`health=`,
+		`health down`,
+		`(health=down`,
+		`health=down)`,
+		`health=down and`,
+		`health=down or or job=node`,
+		`job=~"("`,
+		`job="node`,
+		`job='node`,
+		`job="\q"`,
+		`1job=node`,
+		`job==node`,
+		`job!node`,
+	}
+
+	for _, expr := range tt {
+		_, err := ParseExpr(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,206 @@
+package prometheus
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// promiLabels returns the labels added to targets by promi.
+func promiLabels() []string {
+	return []string{sourceLabelName, staleLabelName, staleAgeLabelName, healthConflictLabelName}
+}
+
+// Metadata is the metadata of a metric on a prometheus server.
+type Metadata struct {
+	Metric string        `json:"metric"`
+	Server string        `json:"server"`
+	Type   v1.MetricType `json:"type"`
+	Unit   string        `json:"unit"`
+	Help   string        `json:"help"`
+	// Conflict is true if the servers or the targets of a server disagree on the metadata of the metric.
+	Conflict bool `json:"conflict"`
+}
+
+// Header represents a metadata header.
+func (m Metadata) Header() []string {
+	return []string{"METRIC", "SERVER", "TYPE", "UNIT", "HELP", "CONFLICT"}
+}
+
+// Row represents a metadata row.
+func (m Metadata) Row() []string {
+	col := color.New(color.FgGreen).SprintFunc()
+
+	if m.Conflict {
+		col = color.New(color.FgRed).SprintFunc()
+	}
+
+	return []string{m.Metric, m.Server, string(m.Type), m.Unit, m.Help, col(strconv.FormatBool(m.Conflict))}
+}
+
+// Metadatas is a list of metric metadata.
+type Metadatas []Metadata
+
+// Conflicts returns the metadata of metrics with conflicts.
+func (m Metadatas) Conflicts() Metadatas {
+	result := Metadatas{}
+
+	for _, md := range m {
+		if md.Conflict {
+			result = append(result, md)
+		}
+	}
+
+	return result
+}
+
+// Metadata returns the metadata of a metric or of all metrics if metric is empty of all
+// prometheus servers. Errors of single servers are returned as warnings, an error is
+// returned only if all servers fail.
+func (c *Client) Metadata(ctx context.Context, metric string) (Metadatas, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		md, err := client.Metadata(ctx, metric, "")
+		return md, nil, err
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	type key struct {
+		typ        v1.MetricType
+		unit, help string
+	}
+
+	result := Metadatas{}
+	variants := map[string]map[key]bool{}
+
+	for _, r := range results {
+		for name, mds := range r.value.(map[string][]v1.Metadata) {
+			if variants[name] == nil {
+				variants[name] = map[key]bool{}
+			}
+
+			seen := map[key]bool{}
+
+			for _, md := range mds {
+				k := key{md.Type, md.Unit, md.Help}
+				variants[name][k] = true
+
+				if seen[k] {
+					continue
+				}
+
+				seen[k] = true
+
+				result = append(result, Metadata{Metric: name, Server: r.server, Type: md.Type, Unit: md.Unit, Help: md.Help})
+			}
+		}
+	}
+
+	for i := range result {
+		result[i].Conflict = len(variants[result[i].Metric]) > 1
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Metric != result[j].Metric {
+			return result[i].Metric < result[j].Metric
+		}
+
+		return result[i].Server < result[j].Server
+	})
+
+	return result, warnings, nil
+}
+
+// TargetMetadata is the metadata of a metric exposed by a target.
+type TargetMetadata struct {
+	Server    string        `json:"server"`
+	Job       string        `json:"job"`
+	ScrapeURL string        `json:"scrapeUrl"`
+	Metric    string        `json:"metric"`
+	Type      v1.MetricType `json:"type"`
+	Unit      string        `json:"unit"`
+	Help      string        `json:"help"`
+}
+
+// Header represents a target metadata header.
+func (m TargetMetadata) Header() []string {
+	return []string{"SERVER", "JOB", "SCRAPE_URL", "METRIC", "TYPE", "UNIT", "HELP"}
+}
+
+// Row represents a target metadata row.
+func (m TargetMetadata) Row() []string {
+	return []string{m.Server, m.Job, m.ScrapeURL, m.Metric, string(m.Type), m.Unit, m.Help}
+}
+
+// TargetsMetadata returns the metadata of the metrics exposed by the targets sorted by
+// server, job, scrape url and metric. If metric is not empty, only the metadata of the
+// metric is returned.
+func (c *Client) TargetsMetadata(ctx context.Context, targets Targets, metric string) ([]TargetMetadata, v1.Warnings, error) {
+	index := map[string]map[model.Fingerprint]Target{}
+	promi := promiLabels()
+
+	for _, t := range targets {
+		server := t.getSource()
+		if index[server] == nil {
+			index[server] = map[model.Fingerprint]Target{}
+		}
+
+		index[server][withoutLabels(t.Labels, promi).Fingerprint()] = t
+	}
+
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		md, err := client.TargetsMetadata(ctx, "", metric, "")
+		return md, nil, err
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	result := []TargetMetadata{}
+
+	for _, r := range results {
+		for _, md := range r.value.([]v1.MetricMetadata) {
+			lset := model.LabelSet{}
+			for n, v := range md.Target {
+				lset[model.LabelName(n)] = model.LabelValue(v)
+			}
+
+			t, ok := index[r.server][lset.Fingerprint()]
+			if !ok {
+				continue
+			}
+
+			result = append(result, TargetMetadata{
+				Server:    r.server,
+				Job:       t.Job(),
+				ScrapeURL: t.ScrapeURL,
+				Metric:    md.Metric,
+				Type:      md.Type,
+				Unit:      md.Unit,
+				Help:      md.Help,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+
+		switch {
+		case a.Server != b.Server:
+			return a.Server < b.Server
+		case a.Job != b.Job:
+			return a.Job < b.Job
+		case a.ScrapeURL != b.ScrapeURL:
+			return a.ScrapeURL < b.ScrapeURL
+		}
+
+		return a.Metric < b.Metric
+	})
+
+	return result, warnings, nil
+}
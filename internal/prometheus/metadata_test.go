@@ -0,0 +1,79 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	newServer := func(help string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/targets":
+				fmt.Fprintln(w, `{"status":"success","data":{"activeTargets":[
+					{"labels":{"instance":"n1:9100","job":"node"},"scrapePool":"node","scrapeUrl":"http://n1:9100/metrics","health":"up"},
+					{"labels":{"instance":"w1:80","job":"web"},"scrapePool":"web","scrapeUrl":"http://w1/metrics","health":"up"}]}}`)
+			case "/api/v1/targets/metadata":
+				fmt.Fprintln(w, `{"status":"success","data":[
+					{"target":{"instance":"n1:9100","job":"node"},"metric":"node_load1","type":"gauge","help":"1m load average.","unit":""},
+					{"target":{"instance":"w1:80","job":"web"},"metric":"http_requests_total","type":"counter","help":"Requests.","unit":""}]}`)
+			case "/api/v1/metadata":
+				fmt.Fprintf(w, `{"status":"success","data":{"node_load1":[{"type":"gauge","help":"%s","unit":""}],"up":[{"type":"gauge","help":"Up.","unit":""},{"type":"gauge","help":"Up.","unit":""}]}}`, help)
+			}
+		}))
+	}
+
+	s1 := newServer("1m load average.")
+	defer s1.Close()
+
+	s2 := newServer("Load average.")
+	defer s2.Close()
+
+	c, err := New([]string{s1.URL, s2.URL}, WithRetries(0, 0))
+	require.NoError(t, err)
+
+	t.Run("metadata conflicts", func(t *testing.T) {
+		metadata, warnings, err := c.Metadata(context.Background(), "")
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+
+		require.Len(t, metadata, 4, "identical metadata of a server must be returned once")
+		assert.Equal(t, "node_load1", metadata[0].Metric)
+		assert.Equal(t, "up", metadata[3].Metric)
+
+		conflicts := metadata.Conflicts()
+		require.Len(t, conflicts, 2)
+
+		for _, md := range conflicts {
+			assert.Equal(t, "node_load1", md.Metric)
+		}
+	})
+
+	t.Run("target metadata", func(t *testing.T) {
+		targets, _, err := c.Targets(context.Background(), false)
+		require.NoError(t, err)
+
+		targets = targets.Filter(TargetByJob(regexp.MustCompile("node")))
+		require.Len(t, targets, 2)
+
+		metadata, _, err := c.TargetsMetadata(context.Background(), targets, "")
+		require.NoError(t, err)
+
+		require.Len(t, metadata, 2)
+
+		for _, md := range metadata {
+			assert.Equal(t, "node", md.Job)
+			assert.Equal(t, "http://n1:9100/metrics", md.ScrapeURL)
+			assert.Equal(t, "node_load1", md.Metric)
+		}
+
+		assert.NotEqual(t, metadata[0].Server, metadata[1].Server)
+	})
+}
@@ -0,0 +1,389 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// ParseTime parses a time as unix timestamp in seconds (float with millisecond
+// precision) or in RFC3339 format like the prometheus api.
+func ParseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, ns := math.Modf(t)
+		ns = math.Round(ns*1000) / 1000
+
+		return time.Unix(int64(sec), int64(ns*float64(time.Second))).UTC(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// Query evaluates an instant query on all prometheus servers. The series are labeled
+// with the prometheus server. Errors of single servers are returned as warnings, an
+// error is returned only if all servers fail.
+func (c *Client) Query(ctx context.Context, query string, ts time.Time) (model.Value, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		return client.Query(ctx, query, ts)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return mergeValues(results), warnings, nil
+}
+
+// QueryRange evaluates a range query on all prometheus servers. The series are labeled
+// with the prometheus server. Errors of single servers are returned as warnings, an
+// error is returned only if all servers fail.
+func (c *Client) QueryRange(ctx context.Context, query string, r v1.Range) (model.Value, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		return client.QueryRange(ctx, query, r)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return mergeValues(results), warnings, nil
+}
+
+// Series returns the series matching any of the matchers of all prometheus servers.
+// The series are labeled with the prometheus server.
+func (c *Client) Series(ctx context.Context, matches []string, start, end time.Time) ([]model.LabelSet, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		return client.Series(ctx, matches, start, end)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	series := []model.LabelSet{}
+
+	for _, r := range results {
+		for _, s := range r.value.([]model.LabelSet) {
+			if s == nil {
+				s = model.LabelSet{}
+			}
+
+			s[sourceLabelName] = model.LabelValue(r.server)
+			series = append(series, s)
+		}
+	}
+
+	return series, warnings, nil
+}
+
+// LabelNames returns the sorted label names of all prometheus servers including the
+// server label.
+func (c *Client) LabelNames(ctx context.Context, matches []string, start, end time.Time) ([]string, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		return client.LabelNames(ctx, matches, start, end)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	set := map[string]bool{sourceLabelName: true}
+
+	for _, r := range results {
+		for _, n := range r.value.([]string) {
+			set[n] = true
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	return names, warnings, nil
+}
+
+// LabelValues returns the sorted values of a label of all prometheus servers. The values
+// of the server label are the names of all servers.
+func (c *Client) LabelValues(ctx context.Context, label string, matches []string, start, end time.Time) (model.LabelValues, v1.Warnings, error) {
+	if label == sourceLabelName {
+		values := model.LabelValues{}
+		for _, s := range c.ServerNames() {
+			values = append(values, model.LabelValue(s))
+		}
+
+		return values, nil, nil
+	}
+
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		return client.LabelValues(ctx, label, matches, start, end)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	set := map[model.LabelValue]bool{}
+
+	for _, r := range results {
+		for _, v := range r.value.(model.LabelValues) {
+			set[v] = true
+		}
+	}
+
+	values := make(model.LabelValues, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+
+	sort.Sort(values)
+
+	return values, warnings, nil
+}
+
+// serverResult is the result of a request to a prometheus server.
+type serverResult struct {
+	server string
+	value  interface{}
+}
+
+// queryAll calls f for all prometheus servers concurrently and returns the results sorted
+// by server. Errors and warnings of single servers are returned as warnings prefixed with
+// the server. An error is returned only if all servers fail.
+func (c *Client) queryAll(ctx context.Context, f func(context.Context, v1.API) (interface{}, v1.Warnings, error)) ([]serverResult, v1.Warnings, error) {
+	clients := c.apis()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = []serverResult{}
+		warnings = v1.Warnings{}
+		errs     = map[string]error{}
+	)
+
+	for server, client := range clients {
+		server, client := server, client
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			v, w, err := f(serverCtx, client)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, s := range w {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", server, s))
+			}
+
+			if err != nil {
+				errs[server] = err
+				warnings = append(warnings, fmt.Sprintf("%s: %s", server, err))
+
+				return
+			}
+
+			results = append(results, serverResult{server: server, value: v})
+		}()
+	}
+
+	wg.Wait()
+
+	sort.Strings(warnings)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].server < results[j].server
+	})
+
+	if len(results) == 0 && len(errs) > 0 {
+		servers := make([]string, 0, len(errs))
+		for s := range errs {
+			servers = append(servers, s)
+		}
+
+		sort.Strings(servers)
+
+		return nil, nil, fmt.Errorf("%s: %w", servers[0], errs[servers[0]])
+	}
+
+	return results, warnings, nil
+}
+
+// mergeValues merges the query results of the prometheus servers. The samples and series
+// of vectors and matrices are labeled with the server and concatenated, of scalars and
+// strings the result of the first server is returned.
+func mergeValues(results []serverResult) model.Value {
+	var merged model.Value
+
+	for _, r := range results {
+		switch v := r.value.(type) {
+		case model.Vector:
+			vector, _ := merged.(model.Vector)
+			if vector == nil {
+				vector = model.Vector{}
+			}
+
+			for _, s := range v {
+				if s.Metric == nil {
+					s.Metric = model.Metric{}
+				}
+
+				s.Metric[sourceLabelName] = model.LabelValue(r.server)
+				vector = append(vector, s)
+			}
+
+			merged = vector
+		case model.Matrix:
+			matrix, _ := merged.(model.Matrix)
+			if matrix == nil {
+				matrix = model.Matrix{}
+			}
+
+			for _, s := range v {
+				if s.Metric == nil {
+					s.Metric = model.Metric{}
+				}
+
+				s.Metric[sourceLabelName] = model.LabelValue(r.server)
+				matrix = append(matrix, s)
+			}
+
+			merged = matrix
+		default:
+			if merged == nil {
+				merged = v.(model.Value)
+			}
+		}
+	}
+
+	if merged == nil {
+		return model.Vector{}
+	}
+
+	return merged
+}
+
+// DeduplicateValue removes the series of HA replicas from a merged query result. Series
+// are identical if their labels without the server and replica labels are equal. Of a
+// vector the sample of the first server is kept, of a matrix the series with most
+// samples. The replica labels are removed and the sources of all identical series are
+// joined in the sourceLabelName label.
+func DeduplicateValue(v model.Value, replicaLabels ...string) model.Value {
+	switch v := v.(type) {
+	case model.Vector:
+		vector := model.Vector{}
+		index := map[model.Fingerprint]int{}
+		sources := [][]string{}
+
+		for _, s := range v {
+			fp := replicaFingerprint(model.LabelSet(s.Metric), replicaLabels)
+			src := string(s.Metric[sourceLabelName])
+
+			if i, ok := index[fp]; ok {
+				sources[i] = append(sources[i], src)
+				continue
+			}
+
+			index[fp] = len(vector)
+			sources = append(sources, []string{src})
+			s.Metric = model.Metric(withoutLabels(model.LabelSet(s.Metric), replicaLabels))
+			vector = append(vector, s)
+		}
+
+		for i := range vector {
+			setSources(model.LabelSet(vector[i].Metric), sources[i])
+		}
+
+		return vector
+	case model.Matrix:
+		matrix := model.Matrix{}
+		index := map[model.Fingerprint]int{}
+		sources := [][]string{}
+
+		for _, s := range v {
+			fp := replicaFingerprint(model.LabelSet(s.Metric), replicaLabels)
+			src := string(s.Metric[sourceLabelName])
+			s.Metric = model.Metric(withoutLabels(model.LabelSet(s.Metric), replicaLabels))
+
+			i, ok := index[fp]
+			if !ok {
+				index[fp] = len(matrix)
+				sources = append(sources, []string{src})
+				matrix = append(matrix, s)
+
+				continue
+			}
+
+			sources[i] = append(sources[i], src)
+
+			if len(s.Values) > len(matrix[i].Values) {
+				matrix[i] = s
+			}
+		}
+
+		for i := range matrix {
+			setSources(model.LabelSet(matrix[i].Metric), sources[i])
+		}
+
+		return matrix
+	}
+
+	return v
+}
+
+// setSources sets the joined sources in the sourceLabelName label of a deduplicated
+// series. Series without sources are not changed.
+func setSources(l model.LabelSet, sources []string) {
+	if src := joinSources(sources...); src != "" {
+		l[sourceLabelName] = model.LabelValue(src)
+	}
+}
+
+// DeduplicateSeries removes the series of HA replicas. Series are identical if their
+// labels without the server and replica labels are equal. The replica labels are removed.
+func DeduplicateSeries(series []model.LabelSet, replicaLabels ...string) []model.LabelSet {
+	result := []model.LabelSet{}
+	seen := map[model.Fingerprint]bool{}
+
+	for _, s := range series {
+		fp := replicaFingerprint(s, replicaLabels)
+		if seen[fp] {
+			continue
+		}
+
+		seen[fp] = true
+
+		result = append(result, withoutLabels(s, replicaLabels))
+	}
+
+	return result
+}
+
+// replicaFingerprint returns the fingerprint of the labels without the server and
+// replica labels.
+func replicaFingerprint(l model.LabelSet, replicaLabels []string) model.Fingerprint {
+	return withoutLabels(l, append([]string{sourceLabelName}, replicaLabels...)).Fingerprint()
+}
+
+// withoutLabels returns a copy of the labels without the names.
+func withoutLabels(l model.LabelSet, names []string) model.LabelSet {
+	c := l.Clone()
+
+	for _, n := range names {
+		delete(c, model.LabelName(n))
+	}
+
+	return c
+}
@@ -0,0 +1,130 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	replica := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/query":
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"node","replica":"%s"},"value":[1628763259,"1"]}]}}`, name)
+			case "/api/v1/labels":
+				fmt.Fprintf(w, `{"status":"success","data":["job","replica"],"warnings":["too many series"]}`)
+			case "/api/v1/label/replica/values":
+				fmt.Fprintf(w, `{"status":"success","data":["%s"]}`, name)
+			case "/api/v1/series":
+				fmt.Fprintf(w, `{"status":"success","data":[{"__name__":"up","job":"node","replica":"%s"}]}`, name)
+			}
+		}))
+	}
+
+	s1 := replica("a")
+	defer s1.Close()
+
+	s2 := replica("b")
+	defer s2.Close()
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+	}))
+	defer s3.Close()
+
+	server1, server2, server3 := "127.0.0.1:"+port(t, s1.URL), "127.0.0.1:"+port(t, s2.URL), "127.0.0.1:"+port(t, s3.URL)
+
+	cli, err := New([]string{s1.URL, s2.URL, s3.URL}, WithRetries(0, 0))
+	require.NoError(t, err)
+
+	t.Run("the samples must be merged and labeled with the server", func(t *testing.T) {
+		v, warnings, err := cli.Query(context.Background(), "up", time.Now())
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], server3)
+
+		vector, ok := v.(model.Vector)
+		require.True(t, ok)
+		require.Len(t, vector, 2)
+		assert.ElementsMatch(t, []model.LabelValue{"a", "b"}, []model.LabelValue{vector[0].Metric["replica"], vector[1].Metric["replica"]})
+
+		sources := []model.LabelValue{vector[0].Metric[sourceLabelName], vector[1].Metric[sourceLabelName]}
+		assert.ElementsMatch(t, []model.LabelValue{model.LabelValue(server1), model.LabelValue(server2)}, sources)
+
+		dedup := DeduplicateValue(v, "replica").(model.Vector)
+		require.Len(t, dedup, 1)
+		assert.Equal(t, model.Metric{"job": "node", sourceLabelName: model.LabelValue(joinSources(server1, server2))}, dedup[0].Metric, "the sources of the replicas must be joined")
+	})
+
+	t.Run("the series must be merged and labeled with the server", func(t *testing.T) {
+		series, _, err := cli.Series(context.Background(), []string{"up"}, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+		require.Len(t, series, 2)
+		assert.Len(t, DeduplicateSeries(series, "replica"), 1)
+	})
+
+	t.Run("the label names and values must be merged", func(t *testing.T) {
+		names, warnings, err := cli.LabelNames(context.Background(), nil, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"job", sourceLabelName, "replica"}, names)
+		assert.Len(t, warnings, 3)
+
+		values, _, err := cli.LabelValues(context.Background(), "replica", nil, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, model.LabelValues{"a", "b"}, values)
+
+		values, _, err = cli.LabelValues(context.Background(), sourceLabelName, nil, time.Unix(0, 0), time.Now())
+		require.NoError(t, err)
+		assert.ElementsMatch(t, model.LabelValues{model.LabelValue(server1), model.LabelValue(server2), model.LabelValue(server3)}, values)
+	})
+
+	t.Run("an error must be returned if all servers fail", func(t *testing.T) {
+		c, err := New([]string{s3.URL})
+		require.NoError(t, err)
+
+		_, _, err = c.Query(context.Background(), "up{", time.Now())
+		require.Error(t, err)
+
+		var apiErr *v1.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, v1.ErrBadData, apiErr.Type)
+	})
+}
+
+func TestParseTime(t *testing.T) {
+	ts, err := ParseTime("1600000000.1234")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1600000000, int64(123*time.Millisecond)).UTC(), ts, "unix timestamps must be rounded to milliseconds")
+
+	ts, err = ParseTime("2020-09-13T12:26:40.5Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1600000000, int64(500*time.Millisecond)).UTC(), ts.UTC())
+
+	_, err = ParseTime("yesterday")
+	assert.Error(t, err)
+}
+
+func TestDeduplicateMatrix(t *testing.T) {
+	m := model.Matrix{
+		{Metric: model.Metric{"job": "node", "replica": "a", sourceLabelName: "prom1"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}}},
+		{Metric: model.Metric{"job": "node", "replica": "b", sourceLabelName: "prom2"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 1}}},
+		{Metric: model.Metric{"job": "web", "replica": "a", sourceLabelName: "prom1"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}}},
+	}
+
+	dedup := DeduplicateValue(m, "replica").(model.Matrix)
+	require.Len(t, dedup, 2)
+	assert.Equal(t, model.Metric{"job": "node", sourceLabelName: "prom1,prom2"}, dedup[0].Metric)
+	assert.Len(t, dedup[0].Values, 2)
+	assert.Equal(t, model.Metric{"job": "web", sourceLabelName: "prom1"}, dedup[1].Metric)
+}
+
@@ -0,0 +1,217 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Rules is a slice of prometheus rules.
+type Rules []Rule
+
+// Rule is an alerting or recording rule of a prometheus server.
+type Rule struct {
+	Name           string         `json:"name"`
+	Type           v1.RuleType    `json:"type"`
+	Group          string         `json:"group"`
+	File           string         `json:"file"`
+	Query          string         `json:"query"`
+	Duration       float64        `json:"duration,omitempty"`
+	Labels         model.LabelSet `json:"labels"`
+	Annotations    model.LabelSet `json:"annotations,omitempty"`
+	Health         v1.RuleHealth  `json:"health"`
+	LastError      string         `json:"lastError,omitempty"`
+	State          string         `json:"state,omitempty"`
+	EvaluationTime float64        `json:"evaluationTime"`
+	LastEvaluation time.Time      `json:"lastEvaluation"`
+}
+
+// Server returns the prometheus server of the rule.
+func (r Rule) Server() string {
+	return string(r.Labels[sourceLabelName])
+}
+
+// Header represents a rule header.
+func (r Rule) Header() []string {
+	return []string{"SERVER", "GROUP", "NAME", "TYPE", "LAST_EVALUATION", "LAST_ERROR", "HEALTH"}
+}
+
+// Row represents a rule row.
+func (r Rule) Row() []string {
+	col := color.New(color.FgGreen).SprintFunc()
+
+	if r.Health == v1.RuleHealthBad {
+		col = color.New(color.FgRed).SprintFunc()
+	}
+
+	if r.Health == v1.RuleHealthUnknown {
+		col = color.New(color.FgYellow).SprintFunc()
+	}
+
+	return []string{r.Server(), r.Group, r.Name, string(r.Type), time.Since(r.LastEvaluation).String(), r.LastError, col(string(r.Health))}
+}
+
+// RuleFilterFunc is a function to filter rules. If function returns true
+// rule is selected else omitted.
+type RuleFilterFunc func(Rule) bool
+
+// Filter filters Rules with RuleFilterFunc.
+func (r Rules) Filter(filters ...RuleFilterFunc) Rules {
+	rules := Rules{}
+
+	for i := range r {
+		selectRule := true
+		for _, f := range filters {
+			selectRule = selectRule && f(r[i])
+		}
+
+		if selectRule {
+			rules = append(rules, r[i])
+		}
+	}
+
+	return rules
+}
+
+// RuleByServer filters Rules by prometheus server.
+func RuleByServer(r *regexp.Regexp) RuleFilterFunc {
+	return func(rule Rule) bool {
+		return r.MatchString(rule.Server())
+	}
+}
+
+// RuleByName filters Rules by name.
+func RuleByName(r *regexp.Regexp) RuleFilterFunc {
+	return func(rule Rule) bool {
+		return r.MatchString(rule.Name)
+	}
+}
+
+// RuleByGroup filters Rules by group name.
+func RuleByGroup(r *regexp.Regexp) RuleFilterFunc {
+	return func(rule Rule) bool {
+		return r.MatchString(rule.Group)
+	}
+}
+
+// RuleBySelector filters Rules by labels selector.
+func RuleBySelector(sel labels.Selector) RuleFilterFunc {
+	return func(rule Rule) bool {
+		return sel.Matches(k8sLabels{rule.Labels})
+	}
+}
+
+// Sort sorts rules by server, group and name.
+func (r Rules) Sort() {
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].Server() != r[j].Server() {
+			return r[i].Server() < r[j].Server()
+		}
+
+		if r[i].Group != r[j].Group {
+			return r[i].Group < r[j].Group
+		}
+
+		return r[i].Name < r[j].Name
+	})
+}
+
+// Rules returns the rules of all prometheus servers.
+func (c *Client) Rules(ctx context.Context) (Rules, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	clients := c.apis()
+	results := make(chan Rules, len(clients))
+
+	for server, client := range clients {
+		server, client := server, client
+
+		g.Go(func() error {
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			r, err := client.Rules(serverCtx)
+			if err != nil {
+				return err
+			}
+
+			rules, err := newRules(server, r)
+			if err != nil {
+				return err
+			}
+
+			results <- rules
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	close(results)
+
+	rules := Rules{}
+	for r := range results {
+		rules = append(rules, r...)
+	}
+
+	return rules, nil
+}
+
+// newRules converts the rule groups of a server.
+func newRules(server string, r v1.RulesResult) (Rules, error) {
+	rules := Rules{}
+
+	for _, g := range r.Groups {
+		for _, rule := range g.Rules {
+			var n Rule
+
+			switch v := rule.(type) {
+			case v1.AlertingRule:
+				n = Rule{
+					Name:           v.Name,
+					Type:           v1.RuleTypeAlerting,
+					Query:          v.Query,
+					Duration:       v.Duration,
+					Labels:         v.Labels.Clone(),
+					Annotations:    v.Annotations,
+					Health:         v.Health,
+					LastError:      v.LastError,
+					State:          v.State,
+					EvaluationTime: v.EvaluationTime,
+					LastEvaluation: v.LastEvaluation,
+				}
+			case v1.RecordingRule:
+				n = Rule{
+					Name:           v.Name,
+					Type:           v1.RuleTypeRecording,
+					Query:          v.Query,
+					Labels:         v.Labels.Clone(),
+					Health:         v.Health,
+					LastError:      v.LastError,
+					EvaluationTime: v.EvaluationTime,
+					LastEvaluation: v.LastEvaluation,
+				}
+			default:
+				return nil, fmt.Errorf("unknown rule type %T", rule)
+			}
+
+			n.Group = g.Name
+			n.File = g.File
+			n.Labels[sourceLabelName] = model.LabelValue(server)
+
+			rules = append(rules, n)
+		}
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,89 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, rules1)
+	}))
+
+	cli, err := New([]string{s1.URL})
+	require.NoError(t, err)
+	rules, err := cli.Rules(context.Background())
+	require.NoError(t, err)
+	rules.Sort()
+
+	require.Len(t, rules, 2)
+
+	t.Run("alerting rules must be converted", func(t *testing.T) {
+		assert.Equal(t, "InstanceDown", rules[0].Name)
+		assert.Equal(t, v1.RuleTypeAlerting, rules[0].Type)
+		assert.Equal(t, "node", rules[0].Group)
+		assert.Equal(t, "firing", rules[0].State)
+		assert.Equal(t, "127.0.0.1:"+port(t, s1.URL), rules[0].Server())
+	})
+
+	t.Run("recording rules must be converted", func(t *testing.T) {
+		assert.Equal(t, v1.RuleTypeRecording, rules[1].Type)
+		assert.Equal(t, v1.RuleHealth(v1.RuleHealthBad), rules[1].Health)
+		assert.Equal(t, "bad query", rules[1].LastError)
+	})
+
+	t.Run("the filtered rules must contain exactly one rule", func(t *testing.T) {
+		assert.Len(t, rules.Filter(RuleByName(regexp.MustCompile("^job:"))), 1)
+	})
+}
+
+var rules1 = `
+{
+  "status": "success",
+  "data": {
+    "groups": [
+      {
+        "name": "node",
+        "file": "/etc/prometheus/rules/node.yml",
+        "interval": 60,
+        "rules": [
+          {
+            "type": "recording",
+            "name": "job:up:sum",
+            "query": "sum by(job) (up)",
+            "health": "err",
+            "lastError": "bad query",
+            "evaluationTime": 0.001,
+            "lastEvaluation": "2021-08-12T10:14:19Z"
+          },
+          {
+            "type": "alerting",
+            "name": "InstanceDown",
+            "query": "up == 0",
+            "duration": 300,
+            "labels": {
+              "severity": "critical"
+            },
+            "annotations": {
+              "summary": "instance down"
+            },
+            "alerts": [],
+            "health": "ok",
+            "evaluationTime": 0.001,
+            "lastEvaluation": "2021-08-12T10:14:19Z",
+            "state": "firing"
+          }
+        ]
+      }
+    ]
+  }
+}
+`
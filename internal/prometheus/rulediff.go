@@ -0,0 +1,257 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// RuleDiffKind is the kind of a difference of the rules of prometheus servers.
+type RuleDiffKind string
+
+// The kinds of rule differences.
+const (
+	RuleDiffMissingGroup RuleDiffKind = "missing-group"
+	RuleDiffMissingRule  RuleDiffKind = "missing-rule"
+	RuleDiffQuery        RuleDiffKind = "query"
+	RuleDiffDuration     RuleDiffKind = "for"
+	RuleDiffLabels       RuleDiffKind = "labels"
+)
+
+// RuleDiffs is a slice of rule differences.
+type RuleDiffs []RuleDiff
+
+// RuleDiff is a rule group or rule that is missing on some servers or a rule whose
+// definition differs between servers.
+type RuleDiff struct {
+	Group string       `json:"group"`
+	Name  string       `json:"name,omitempty"`
+	Kind  RuleDiffKind `json:"kind"`
+	// Missing are the servers without the group or rule.
+	Missing []string `json:"missing,omitempty"`
+	// Values are the servers by differing value of the query, for duration or labels.
+	Values map[string][]string `json:"values,omitempty"`
+}
+
+// Header represents a rule diff header.
+func (d RuleDiff) Header() []string {
+	return []string{"GROUP", "NAME", "DIFFERENCE", "DETAILS"}
+}
+
+// Row represents a rule diff row.
+func (d RuleDiff) Row() []string {
+	return []string{d.Group, d.Name, string(d.Kind), d.details()}
+}
+
+// details returns the missing servers or the values with their servers.
+func (d RuleDiff) details() string {
+	if len(d.Missing) > 0 {
+		return "missing on " + strings.Join(d.Missing, ",")
+	}
+
+	values := make([]string, 0, len(d.Values))
+
+	for v, servers := range d.Values {
+		values = append(values, fmt.Sprintf("%s on %s", strings.Join(strings.Fields(v), " "), strings.Join(servers, ",")))
+	}
+
+	sort.Strings(values)
+
+	return strings.Join(values, "; ")
+}
+
+// Diff compares the rules of the prometheus servers. It reports rule groups and rules
+// (identified by group and name) that exist on some servers but not on others, and
+// rules whose query, for duration or labels differ. Rules of a missing group are not
+// reported again. The servers are the servers to compare, if empty the servers of the
+// rules are used. The rules are compared unfiltered, the filters only select the
+// reported differences: a difference is reported if one of its rules matches all filters.
+func (r Rules) Diff(servers []string, filters ...RuleFilterFunc) RuleDiffs {
+	if len(servers) == 0 {
+		servers = r.servers()
+	}
+
+	// group -> name -> server -> rules
+	groups := map[string]map[string]map[string]Rules{}
+
+	for _, rule := range r {
+		if _, ok := groups[rule.Group]; !ok {
+			groups[rule.Group] = map[string]map[string]Rules{}
+		}
+
+		if _, ok := groups[rule.Group][rule.Name]; !ok {
+			groups[rule.Group][rule.Name] = map[string]Rules{}
+		}
+
+		groups[rule.Group][rule.Name][rule.Server()] = append(groups[rule.Group][rule.Name][rule.Server()], rule)
+	}
+
+	diffs := RuleDiffs{}
+
+	for group, rules := range groups {
+		diffs = append(diffs, diffGroup(group, rules, servers, filters)...)
+	}
+
+	diffs.Sort()
+
+	return diffs
+}
+
+// diffGroup compares the rules of a group. The rules are by name and server.
+func diffGroup(group string, rules map[string]map[string]Rules, servers []string, filters []RuleFilterFunc) RuleDiffs {
+	diffs := RuleDiffs{}
+	withGroup := map[string]bool{}
+	selected := false
+
+	for _, byServer := range rules {
+		for server := range byServer {
+			withGroup[server] = true
+		}
+
+		selected = selected || matchesAny(byServer, filters)
+	}
+
+	if !selected {
+		return diffs
+	}
+
+	if missing := missingServers(servers, withGroup); len(missing) > 0 {
+		diffs = append(diffs, RuleDiff{Group: group, Kind: RuleDiffMissingGroup, Missing: missing})
+	}
+
+	// only servers with the group are compared, the others are already reported
+	candidates := []string{}
+
+	for _, s := range servers {
+		if withGroup[s] {
+			candidates = append(candidates, s)
+		}
+	}
+
+	for name, byServer := range rules {
+		if matchesAny(byServer, filters) {
+			diffs = append(diffs, diffRule(group, name, byServer, candidates)...)
+		}
+	}
+
+	return diffs
+}
+
+// diffRule compares the rules with the same group and name of the candidate servers.
+// The rules are by server.
+func diffRule(group, name string, byServer map[string]Rules, candidates []string) RuleDiffs {
+	diffs := RuleDiffs{}
+	withRule := map[string]bool{}
+
+	for server := range byServer {
+		withRule[server] = true
+	}
+
+	if missing := missingServers(candidates, withRule); len(missing) > 0 {
+		diffs = append(diffs, RuleDiff{Group: group, Name: name, Kind: RuleDiffMissingRule, Missing: missing})
+	}
+
+	fields := []struct {
+		kind  RuleDiffKind
+		value func(Rule) string
+	}{
+		{RuleDiffQuery, func(rule Rule) string { return rule.Query }},
+		{RuleDiffDuration, func(rule Rule) string { return (time.Duration(rule.Duration) * time.Second).String() }},
+		{RuleDiffLabels, func(rule Rule) string { return ruleLabels(rule).String() }},
+	}
+
+	for _, f := range fields {
+		values := map[string][]string{}
+
+		for server, rules := range byServer {
+			v := make([]string, 0, len(rules))
+			for _, rule := range rules {
+				v = append(v, f.value(rule))
+			}
+
+			sort.Strings(v)
+
+			key := strings.Join(v, "\n")
+			values[key] = append(values[key], server)
+		}
+
+		if len(values) < 2 {
+			continue
+		}
+
+		for _, s := range values {
+			sort.Strings(s)
+		}
+
+		diffs = append(diffs, RuleDiff{Group: group, Name: name, Kind: f.kind, Values: values})
+	}
+
+	return diffs
+}
+
+// matchesAny returns true if one of the rules matches all filters.
+func matchesAny(byServer map[string]Rules, filters []RuleFilterFunc) bool {
+	for _, rules := range byServer {
+		if len(rules.Filter(filters...)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sort sorts the rule differences by group, name and kind.
+func (d RuleDiffs) Sort() {
+	sort.SliceStable(d, func(i, j int) bool {
+		if d[i].Group != d[j].Group {
+			return d[i].Group < d[j].Group
+		}
+
+		if d[i].Name != d[j].Name {
+			return d[i].Name < d[j].Name
+		}
+
+		return d[i].Kind < d[j].Kind
+	})
+}
+
+// servers returns the sorted servers of the rules.
+func (r Rules) servers() []string {
+	seen := map[string]bool{}
+	servers := []string{}
+
+	for _, rule := range r {
+		if !seen[rule.Server()] {
+			seen[rule.Server()] = true
+			servers = append(servers, rule.Server())
+		}
+	}
+
+	sort.Strings(servers)
+
+	return servers
+}
+
+// missingServers returns the servers that are not in present.
+func missingServers(servers []string, present map[string]bool) []string {
+	missing := []string{}
+
+	for _, s := range servers {
+		if !present[s] {
+			missing = append(missing, s)
+		}
+	}
+
+	return missing
+}
+
+// ruleLabels returns the labels of the rule without the server label.
+func ruleLabels(rule Rule) model.LabelSet {
+	l := rule.Labels.Clone()
+	delete(l, sourceLabelName)
+
+	return l
+}
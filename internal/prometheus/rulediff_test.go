@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestRulesDiff(t *testing.T) {
+	rule := func(server, group, name, query string, duration float64, severity string) Rule {
+		return Rule{
+			Name:     name,
+			Group:    group,
+			Query:    query,
+			Duration: duration,
+			Labels:   model.LabelSet{sourceLabelName: model.LabelValue(server), "severity": model.LabelValue(severity)},
+		}
+	}
+
+	rules := Rules{
+		rule("prom1", "node", "InstanceDown", "up == 0", 60, "critical"),
+		rule("prom1", "node", "DiskFull", "disk_free < 0.1", 300, "warning"),
+		rule("prom1", "web", "HighLatency", "latency > 1", 0, "warning"),
+		rule("prom2", "node", "InstanceDown", "up == 0", 120, "critical"),
+		rule("prom2", "node", "DiskFull", "disk_free < 0.05", 300, "critical"),
+		rule("prom2", "web", "HighLatency", "latency > 1", 0, "warning"),
+		rule("prom3", "node", "InstanceDown", "up == 0", 60, "critical"),
+	}
+
+	t.Run("identical rules must not be reported", func(t *testing.T) {
+		assert.Empty(t, rules.Filter(func(r Rule) bool { return r.Group == "web" }).Diff(nil))
+	})
+
+	t.Run("missing groups, missing rules and differences must be reported", func(t *testing.T) {
+		assert.Equal(t, RuleDiffs{
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffLabels, Values: map[string][]string{`{severity="warning"}`: {"prom1"}, `{severity="critical"}`: {"prom2"}}},
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffMissingRule, Missing: []string{"prom3"}},
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffQuery, Values: map[string][]string{"disk_free < 0.1": {"prom1"}, "disk_free < 0.05": {"prom2"}}},
+			{Group: "node", Name: "InstanceDown", Kind: RuleDiffDuration, Values: map[string][]string{"1m0s": {"prom1", "prom3"}, "2m0s": {"prom2"}}},
+			{Group: "web", Kind: RuleDiffMissingGroup, Missing: []string{"prom3"}},
+		}, rules.Diff(nil))
+	})
+
+	t.Run("the filters must select the differences and not the compared rules", func(t *testing.T) {
+		// DiskFull is a warning on prom1, it must not be reported as missing there
+		assert.Equal(t, RuleDiffs{
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffLabels, Values: map[string][]string{`{severity="warning"}`: {"prom1"}, `{severity="critical"}`: {"prom2"}}},
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffMissingRule, Missing: []string{"prom3"}},
+			{Group: "node", Name: "DiskFull", Kind: RuleDiffQuery, Values: map[string][]string{"disk_free < 0.1": {"prom1"}, "disk_free < 0.05": {"prom2"}}},
+			{Group: "node", Name: "InstanceDown", Kind: RuleDiffDuration, Values: map[string][]string{"1m0s": {"prom1", "prom3"}, "2m0s": {"prom2"}}},
+		}, rules.Diff(nil, RuleBySelector(labels.SelectorFromSet(labels.Set{"severity": "critical"}))))
+	})
+
+	t.Run("servers without rules must be reported as missing", func(t *testing.T) {
+		diffs := rules.Filter(func(r Rule) bool { return r.Group == "web" }).Diff([]string{"prom1", "prom2", "prom4"})
+		assert.Equal(t, RuleDiffs{{Group: "web", Kind: RuleDiffMissingGroup, Missing: []string{"prom4"}}}, diffs)
+	})
+
+	t.Run("the details must list the servers", func(t *testing.T) {
+		assert.Equal(t, "missing on prom3", RuleDiff{Missing: []string{"prom3"}}.details())
+		assert.Equal(t, "1m0s on prom1,prom3; 2m0s on prom2", RuleDiff{Values: map[string][]string{"1m0s": {"prom1", "prom3"}, "2m0s": {"prom2"}}}.details())
+	})
+}
@@ -0,0 +1,162 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// Servers is a slice of prometheus servers.
+type Servers []Server
+
+// Server is the build and runtime information of a prometheus server.
+type Server struct {
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Reachable     bool              `json:"reachable"`
+	Latency       time.Duration     `json:"latency"`
+	Version       string            `json:"version,omitempty"`
+	Revision      string            `json:"revision,omitempty"`
+	StartTime     time.Time         `json:"startTime,omitempty"`
+	Retention     string            `json:"retention,omitempty"`
+	ActiveTargets int               `json:"activeTargets"`
+	Flags         map[string]string `json:"flags,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// Header represents a server header.
+func (s Server) Header() []string {
+	return []string{"NAME", "URL", "REACHABLE", "LATENCY", "VERSION", "REVISION", "START_TIME", "RETENTION", "ACTIVE_TARGETS", "ERROR"}
+}
+
+// Row represents a server row.
+func (s Server) Row() []string {
+	col := color.New(color.FgGreen).SprintFunc()
+
+	if !s.Reachable {
+		col = color.New(color.FgRed).SprintFunc()
+	}
+
+	startTime := ""
+	if !s.StartTime.IsZero() {
+		startTime = s.StartTime.Format(time.RFC3339)
+	}
+
+	return []string{s.Name, s.URL, col(strconv.FormatBool(s.Reachable)), s.Latency.String(), s.Version, s.Revision,
+		startTime, s.Retention, strconv.Itoa(s.ActiveTargets), s.Error}
+}
+
+// Sort sorts servers by name.
+func (s Servers) Sort() {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
+}
+
+// Servers returns the build and runtime information of all prometheus servers. Unlike
+// the other requests, a failing server is no error: it is returned as not reachable
+// with the error of the request.
+func (c *Client) Servers(ctx context.Context) Servers {
+	clients := c.apis()
+	servers := make(Servers, 0, len(clients))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for name, client := range clients {
+		name, client := name, client
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			s := newServer(serverCtx, name, client)
+			s.URL = c.ServerURL(name)
+
+			mu.Lock()
+			servers = append(servers, s)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	servers.Sort()
+
+	return servers
+}
+
+// newServer requests the information of a prometheus server. The latency is the duration
+// of the buildinfo request. If it fails without a response, the server is not reachable
+// and the other requests are skipped. Otherwise the first error is recorded and the
+// remaining information is returned (e.g. buildinfo is not available before prometheus
+// 2.14).
+func newServer(ctx context.Context, name string, client v1.API) Server {
+	s := Server{
+		Name: name,
+	}
+
+	start := time.Now()
+	build, err := client.Buildinfo(ctx)
+	s.Latency = time.Since(start).Round(time.Millisecond)
+
+	var apiErr *v1.Error
+	if err != nil && !(errors.As(err, &apiErr) && apiErr.Type == v1.ErrClient) {
+		s.Error = err.Error()
+		return s
+	}
+
+	s.Reachable = true
+	s.Version = build.Version
+	s.Revision = build.Revision
+
+	setError := func(err error) {
+		if s.Error == "" {
+			s.Error = err.Error()
+		}
+	}
+
+	if err != nil {
+		setError(err)
+	}
+
+	info, err := client.Runtimeinfo(ctx)
+	if err != nil {
+		setError(err)
+	}
+
+	s.StartTime = info.StartTime
+	s.Retention = info.StorageRetention
+
+	flags, err := client.Flags(ctx)
+	if err != nil {
+		setError(err)
+	}
+
+	s.Flags = flags
+
+	if s.Retention == "" {
+		s.Retention = flags["storage.tsdb.retention.time"]
+	}
+
+	targets, err := client.Targets(ctx)
+	if err != nil {
+		setError(err)
+	}
+
+	s.ActiveTargets = len(targets.Active)
+
+	return s
+}
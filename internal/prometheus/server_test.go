@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServers(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/status/buildinfo":
+			fmt.Fprintln(w, `{"status":"success","data":{"version":"2.28.1","revision":"b0944590"}}`)
+		case "/api/v1/status/runtimeinfo":
+			fmt.Fprintln(w, `{"status":"success","data":{"startTime":"2021-08-01T10:00:00Z","storageRetention":"15d"}}`)
+		case "/api/v1/status/flags":
+			fmt.Fprintln(w, `{"status":"success","data":{"storage.tsdb.retention.time":"15d"}}`)
+		case "/api/v1/targets":
+			fmt.Fprintln(w, targets1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s1.Close()
+
+	// a prometheus server before 2.14 without buildinfo and runtimeinfo
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/status/flags":
+			fmt.Fprintln(w, `{"status":"success","data":{"storage.tsdb.retention.time":"30d"}}`)
+		case "/api/v1/targets":
+			fmt.Fprintln(w, `{"status":"success","data":{"activeTargets":[],"droppedTargets":[]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s2.Close()
+
+	s3 := httptest.NewServer(http.NotFoundHandler())
+	s3.Close()
+
+	cli, err := New([]string{s1.URL, s2.URL, s3.URL})
+	require.NoError(t, err)
+
+	servers := cli.Servers(context.Background())
+	require.Len(t, servers, 3)
+
+	byName := map[string]Server{}
+	for _, s := range servers {
+		byName[s.Name] = s
+	}
+
+	t.Run("the information of a server must be returned", func(t *testing.T) {
+		s := byName["127.0.0.1:"+port(t, s1.URL)]
+		assert.Equal(t, s1.URL, s.URL)
+		assert.True(t, s.Reachable)
+		assert.Equal(t, "2.28.1", s.Version)
+		assert.Equal(t, "b0944590", s.Revision)
+		assert.Equal(t, time.Date(2021, 8, 1, 10, 0, 0, 0, time.UTC), s.StartTime.UTC())
+		assert.Equal(t, "15d", s.Retention)
+		assert.Equal(t, 3, s.ActiveTargets)
+		assert.Empty(t, s.Error)
+	})
+
+	t.Run("a server without buildinfo must be reachable", func(t *testing.T) {
+		s := byName["127.0.0.1:"+port(t, s2.URL)]
+		assert.True(t, s.Reachable)
+		assert.Equal(t, "30d", s.Retention)
+		assert.NotEmpty(t, s.Error)
+	})
+
+	t.Run("a stopped server must not be reachable", func(t *testing.T) {
+		s := byName["127.0.0.1:"+port(t, s3.URL)]
+		assert.False(t, s.Reachable)
+		assert.NotEmpty(t, s.Error)
+	})
+}
@@ -2,15 +2,16 @@ package prometheus
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
-	"golang.org/x/sync/errgroup"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
@@ -20,12 +21,21 @@ const (
 	sourceLabelName = "promi_scrape_src"
 )
 
+// SourceLabelName is the label with the prometheus server of targets, alerts, rules
+// and query results.
+const SourceLabelName = sourceLabelName
+
 // Targets is a slice of prometheus targets.
 type Targets []Target
 
 // Target is prometheus Target.
 type Target struct {
 	v1.ActiveTarget
+	// Stats are the sample counts of the last scrape. They are nil if they are not
+	// requested with Client.TargetStats or no values were found.
+	Stats *TargetStats `json:"stats,omitempty"`
+	// withStats adds the stats columns to the table output.
+	withStats bool
 }
 
 // Job returns the job label value.
@@ -38,9 +48,35 @@ func (t Target) Job() string {
 	return string(job)
 }
 
+// Server returns the prometheus server of the target. For targets representing
+// a prometheus server itself, it is the server.
+func (t Target) Server() string {
+	if t.IsScraper() {
+		return t.ScrapeURL
+	}
+
+	return t.getSource()
+}
+
+// IsScraper returns true if the target represents a prometheus server itself.
+func (t Target) IsScraper() bool {
+	return t.ScrapePool == sourcesJobName
+}
+
+// Key returns a key that identifies a target of a prometheus server.
+func (t Target) Key() string {
+	return t.getSource() + "|" + t.ScrapePool + "|" + t.ScrapeURL
+}
+
 // Header represents a target header.
 func (t Target) Header() []string {
-	return []string{"SERVER", "JOB", "SCRAPE_URL", "LAST_SCRAPE", "LABELS", "LAST_ERROR", "HEALTH"}
+	header := []string{"SERVER", "JOB", "SCRAPE_URL", "LAST_SCRAPE", "LABELS", "LAST_ERROR", "HEALTH"}
+
+	if t.withStats {
+		header = append(header, "SAMPLES", "SERIES_ADDED", "SCRAPE_DURATION")
+	}
+
+	return header
 }
 
 // Row represents a target row.
@@ -58,72 +94,136 @@ func (t Target) Row() []string {
 
 	server := string(t.Labels[sourceLabelName])
 
-	return []string{server, t.Job(), t.ScrapeURL, time.Since(t.LastScrape).String(), t.Labels.String(), t.ActiveTarget.LastError, col(string(t.Health))}
+	row := []string{server, t.Job(), t.ScrapeURL, time.Since(t.LastScrape).String(), t.Labels.String(), t.ActiveTarget.LastError, col(string(t.Health)) + staleness(t.Labels)}
+
+	if t.withStats {
+		row = append(row, t.Stats.row()...)
+	}
+
+	return row
 }
 
 // Targets returns all active targets. If appendScraperAsTarget is true the scraper status
-// is added to the active targets and all errors accessing scrapers are ignored.
-func (c Client) Targets(ctx context.Context, appendScraperAsTarget bool) (Targets, error) {
-	g, ctx := errgroup.WithContext(ctx)
-	results := make(chan result, len(c.clients))
+// is added to the active targets. If a server fails and a cache is configured, the cached
+// targets are returned and marked as stale. Errors of single servers without cached
+// targets are returned as warnings, an error is returned only if all servers fail. If
+// appendScraperAsTarget is true, no error is returned for failing servers.
+func (c *Client) Targets(ctx context.Context, appendScraperAsTarget bool) (Targets, v1.Warnings, error) {
+	if c.cacheOnly {
+		targets, err := mergeTargets(c.cache.targetResults(appendScraperAsTarget))
+		return targets, nil, err
+	}
+
+	clients := c.apis()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = []result{}
+		warnings = v1.Warnings{}
+		errs     = map[string]error{}
+	)
 
-	for server, client := range c.clients {
+	for server, client := range clients {
 		client := client
 		server := server
 
-		g.Go(func() error {
-			start := time.Now()
-			r, err := client.Targets(ctx)
-			if appendScraperAsTarget {
-				a := v1.ActiveTarget{
-					ScrapeURL:  server,
-					ScrapePool: sourcesJobName,
-					GlobalURL:  server,
-					Labels: model.LabelSet{
-						"instance": model.LabelValue(server),
-					},
-					DiscoveredLabels:   map[string]string{},
-					LastScrape:         start,
-					LastScrapeDuration: time.Since(start).Seconds(),
-					Health:             v1.HealthGood,
-				}
+		wg.Add(1)
 
-				if err != nil {
-					a.Health = v1.HealthBad
-					a.LastError = err.Error()
-				}
+		go func() {
+			defer wg.Done()
+
+			serverCtx, cancel := c.serverContext(ctx)
+			defer cancel()
+
+			start := time.Now()
+			r, err := client.Targets(serverCtx)
+			r, age := c.cache.targets(server, r, err)
 
-				r.Active = append(r.Active, a)
+			mu.Lock()
+			defer mu.Unlock()
 
-				err = nil // ingoring errors if we append scrapers as targets
+			if err != nil && age == 0 {
+				errs[server] = err
+				warnings = append(warnings, fmt.Sprintf("%s: %s", server, err))
 			}
 
-			if err != nil {
-				return err
+			if appendScraperAsTarget {
+				r.Active = append(r.Active, scraperTarget(server, start, time.Since(start), err))
+			} else if err != nil && age == 0 {
+				return
 			}
 
-			results <- result{
+			results = append(results, result{
 				server: server,
 				target: r,
-			}
+				age:    age,
+			})
+		}()
+	}
 
-			return nil
-		})
+	wg.Wait()
+
+	c.cache.save()
+
+	if len(errs) > 0 && len(errs) == len(clients) && !appendScraperAsTarget {
+		servers := make([]string, 0, len(errs))
+		for s := range errs {
+			servers = append(servers, s)
+		}
+
+		sort.Strings(servers)
+
+		return nil, nil, fmt.Errorf("%s: %w", servers[0], errs[servers[0]])
+	}
+
+	sort.Strings(warnings)
+
+	targets, err := mergeTargets(results)
+	if err != nil {
+		return nil, warnings, err
 	}
 
-	if err := g.Wait(); err != nil {
-		return nil, err
+	return targets, warnings, nil
+}
+
+// scraperTarget returns a target representing the prometheus server itself.
+func scraperTarget(server string, lastScrape time.Time, duration time.Duration, err error) v1.ActiveTarget {
+	a := v1.ActiveTarget{
+		ScrapeURL:  server,
+		ScrapePool: sourcesJobName,
+		GlobalURL:  server,
+		Labels: model.LabelSet{
+			"instance": model.LabelValue(server),
+		},
+		DiscoveredLabels:   map[string]string{},
+		LastScrape:         lastScrape,
+		LastScrapeDuration: duration.Seconds(),
+		Health:             v1.HealthGood,
 	}
 
-	close(results)
+	if err != nil {
+		a.Health = v1.HealthBad
+		a.LastError = err.Error()
+	}
 
+	return a
+}
+
+// mergeTargets merges the targets of all servers. Targets of stale results are marked
+// with the staleLabelName and staleAgeLabelName labels.
+func mergeTargets(results []result) (Targets, error) {
 	targets := Targets{}
 
-	for r := range results {
+	for _, r := range results {
 		for i := range r.target.Active {
 			activeTarget := r.target.Active[i]
 			if activeTarget.ScrapePool != sourcesJobName {
 				activeTarget.Labels[sourceLabelName] = model.LabelValue(r.server)
+
+				if r.age > 0 {
+					markStale(activeTarget.Labels, r.age)
+				}
 			}
 
 			if err := activeTarget.Labels.Validate(); err != nil {
@@ -198,25 +298,151 @@ func TargetBySelector(selector labels.Selector) TargetFilterFunc {
 	}
 }
 
-// Sort sorts targets by job and scrape url.
-func (t Targets) Sort() {
-	sort.Slice(t, func(i, j int) bool {
-		switch strings.Compare(t[i].Job(), t[j].Job()) {
-		case -1:
-			return true
-		case 1:
-			return false
+// TargetByStaleFor filters Targets whose last scrape is older than d.
+func TargetByStaleFor(d time.Duration) TargetFilterFunc {
+	now := time.Now()
+
+	return func(t Target) bool {
+		return now.Sub(t.LastScrape) > d
+	}
+}
+
+// TargetBySlowerThan filters Targets whose last scrape took longer than d.
+func TargetBySlowerThan(d time.Duration) TargetFilterFunc {
+	return func(t Target) bool {
+		return t.LastScrapeDuration > d.Seconds()
+	}
+}
+
+// TargetByLastError filters Targets by the error of the last scrape.
+func TargetByLastError(r *regexp.Regexp) TargetFilterFunc {
+	return func(t Target) bool {
+		return r.MatchString(t.LastError)
+	}
+}
+
+// TargetSortKey is a key to sort targets.
+type TargetSortKey string
+
+// The target sort keys. Use TargetSortByLabel to sort by a label.
+const (
+	// TargetSortByHealth sorts by health, the worst health first.
+	TargetSortByHealth TargetSortKey = "health"
+	// TargetSortByLastScrape sorts by the age of the last scrape, the most recent scrape first.
+	TargetSortByLastScrape TargetSortKey = "last-scrape"
+	// TargetSortByDuration sorts by the scrape duration, the fastest scrape first.
+	TargetSortByDuration TargetSortKey = "duration"
+	// TargetSortByServer sorts by prometheus server.
+	TargetSortByServer TargetSortKey = "server"
+	// TargetSortByJob sorts by job.
+	TargetSortByJob TargetSortKey = "job"
+	// TargetSortByScrapeURL sorts by scrape url.
+	TargetSortByScrapeURL TargetSortKey = "scrape-url"
+	// TargetSortBySamples sorts by the samples of the last scrape, the fewest samples first.
+	// Targets without stats come first.
+	TargetSortBySamples TargetSortKey = "samples"
+	// TargetSortBySeriesAdded sorts by the series added in the last scrape, the fewest
+	// series first. Targets without stats come first.
+	TargetSortBySeriesAdded TargetSortKey = "series-added"
+
+	labelSortKeyPrefix = "label:"
+)
+
+// TargetSortKeys are all available target sort keys without label keys.
+func TargetSortKeys() []TargetSortKey {
+	return []TargetSortKey{TargetSortByHealth, TargetSortByLastScrape, TargetSortByDuration, TargetSortByServer, TargetSortByJob, TargetSortByScrapeURL,
+		TargetSortBySamples, TargetSortBySeriesAdded}
+}
+
+// TargetSortByLabel returns the key to sort targets by the label.
+func TargetSortByLabel(name string) TargetSortKey {
+	return TargetSortKey(labelSortKeyPrefix + name)
+}
+
+// ParseTargetSortKey parses a sort key. Labels are specified as label:<name>.
+func ParseTargetSortKey(s string) (TargetSortKey, error) {
+	if strings.HasPrefix(s, labelSortKeyPrefix) {
+		if !model.LabelName(strings.TrimPrefix(s, labelSortKeyPrefix)).IsValid() {
+			return "", fmt.Errorf("invalid label name in sort key %q", s)
 		}
-		switch strings.Compare(string(t[i].Labels[sourceLabelName]), string(t[j].Labels[sourceLabelName])) {
-		case -1:
-			return true
-		case 1:
-			return false
+
+		return TargetSortKey(s), nil
+	}
+
+	for _, k := range TargetSortKeys() {
+		if string(k) == s {
+			return k, nil
 		}
-		return t[i].ScrapeURL < t[j].ScrapeURL
+	}
+
+	return "", fmt.Errorf("invalid sort key %q: must be one of %v or label:<name>", s, TargetSortKeys())
+}
+
+// Sort sorts targets by job, server and scrape url.
+func (t Targets) Sort() {
+	t.SortBy(false)
+}
+
+// SortBy sorts targets by the keys. Targets with equal keys are sorted by job, server
+// and scrape url. If reverse is true, the order is reversed.
+func (t Targets) SortBy(reverse bool, keys ...TargetSortKey) {
+	keys = append(keys, TargetSortByJob, TargetSortByServer, TargetSortByScrapeURL)
+
+	sort.SliceStable(t, func(i, j int) bool {
+		for _, key := range keys {
+			if c := t[i].compare(&t[j], key); c != 0 {
+				return (c < 0) != reverse
+			}
+		}
+
+		return false
 	})
 }
 
+// compare compares the targets by key.
+func (t Target) compare(o *Target, key TargetSortKey) int {
+	switch key {
+	case TargetSortByHealth:
+		return healthRank(t.Health) - healthRank(o.Health)
+	case TargetSortByLastScrape:
+		// the most recent scrape has the smallest age
+		switch {
+		case t.LastScrape.After(o.LastScrape):
+			return -1
+		case t.LastScrape.Before(o.LastScrape):
+			return 1
+		}
+
+		return 0
+	case TargetSortByDuration:
+		switch {
+		case t.LastScrapeDuration < o.LastScrapeDuration:
+			return -1
+		case t.LastScrapeDuration > o.LastScrapeDuration:
+			return 1
+		}
+
+		return 0
+	case TargetSortByServer:
+		return strings.Compare(t.getSource(), o.getSource())
+	case TargetSortByJob:
+		return strings.Compare(t.Job(), o.Job())
+	case TargetSortByScrapeURL:
+		return strings.Compare(t.ScrapeURL, o.ScrapeURL)
+	case TargetSortBySamples:
+		return compareFloat(t.Stats.samples(), o.Stats.samples())
+	case TargetSortBySeriesAdded:
+		return compareFloat(t.Stats.seriesAdded(), o.Stats.seriesAdded())
+	}
+
+	if strings.HasPrefix(string(key), labelSortKeyPrefix) {
+		name := model.LabelName(strings.TrimPrefix(string(key), labelSortKeyPrefix))
+		return strings.Compare(string(t.Labels[name]), string(o.Labels[name]))
+	}
+
+	return 0
+}
+
 // Compact removes labels and last error information.
 func (t Targets) Compact() {
 	for i := range t {
@@ -236,35 +462,6 @@ func (t Targets) Active() []*v1.ActiveTarget {
 	return active
 }
 
-// Deduplicate deduplicates targets by scrape url. If identical targets
-// have different health status, then HealthBad status has highest priority.
-// Multiple sources are appended in the sourceLabelName label.
-func (t Targets) Deduplicate() Targets {
-	m := map[string]Target{}
-
-	for i := range t {
-		target := t[i]
-		if tg, ok := m[target.ScrapeURL]; ok {
-			tg.addSource(target.getSource())
-
-			if target.Health == tg.Health || tg.Health == v1.HealthBad {
-				target.addSource(tg.getSource())
-				continue
-			}
-		}
-
-		m[target.ScrapeURL] = target
-	}
-
-	targets := make(Targets, 0, len(m))
-
-	for s := range m {
-		targets = append(targets, m[s])
-	}
-
-	return targets
-}
-
 func (t Target) labels() k8sLabels {
 	return k8sLabels{
 		LabelSet: t.Labels,
@@ -274,6 +471,8 @@ func (t Target) labels() k8sLabels {
 type result struct {
 	target v1.TargetsResult
 	server string
+	// age is the age of stale results, zero for fresh results.
+	age time.Duration
 }
 
 type k8sLabels struct {
@@ -291,23 +490,25 @@ func (l k8sLabels) Get(key string) string {
 	return string(l.LabelSet[model.LabelName(key)])
 }
 
-func (t Target) addSource(src string) {
-	s := string(t.Labels[sourceLabelName])
+// joinSources returns the sorted, comma separated list of the unique sources.
+func joinSources(sources ...string) string {
+	m := map[string]bool{}
+	l := []string{}
 
-	if s == "" || src == "" || strings.Contains(s, src) {
-		return
-	}
+	for _, s := range sources {
+		for _, src := range strings.Split(s, ",") {
+			if src == "" || m[src] {
+				continue
+			}
 
-	l := []string{s}
-	if strings.Contains(s, ",") {
-		l = strings.Split(s, ",")
+			m[src] = true
+			l = append(l, src)
+		}
 	}
 
-	l = append(l, src)
-
 	sort.Strings(l)
 
-	t.Labels[sourceLabelName] = model.LabelValue(strings.Join(l, ","))
+	return strings.Join(l, ",")
 }
 
 func (t Target) getSource() string {
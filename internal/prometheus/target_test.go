@@ -1,11 +1,14 @@
 package prometheus
 
 import (
+	"regexp"
 	"testing"
+	"time"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDeduplicate(t *testing.T) {
@@ -89,7 +92,8 @@ func TestDeduplicate(t *testing.T) {
 				Health:    v1.HealthBad,
 				LastError: "scrapeErr",
 				Labels: model.LabelSet{
-					sourceLabelName: "src1,src2",
+					sourceLabelName:         "src1,src2",
+					healthConflictLabelName: "src1=down,src2=up",
 				},
 			},
 		},
@@ -99,7 +103,8 @@ func TestDeduplicate(t *testing.T) {
 				Health:    v1.HealthBad,
 				LastError: "scrapeErr",
 				Labels: model.LabelSet{
-					sourceLabelName: "src1,src2",
+					sourceLabelName:         "src1,src2",
+					healthConflictLabelName: "src1=down,src2=up",
 				},
 			},
 		},
@@ -107,4 +112,160 @@ func TestDeduplicate(t *testing.T) {
 
 	assert.Equal(t, expected, dedup)
 
+	t.Run("the original targets are not modified", func(t *testing.T) {
+		assert.Equal(t, model.LabelValue("src1"), targets[0].Labels[sourceLabelName])
+	})
+}
+
+func TestDeduplicatePolicy(t *testing.T) {
+	now := time.Now()
+	target := func(src string, health v1.HealthStatus, lastScrape time.Time) Target {
+		return Target{
+			ActiveTarget: v1.ActiveTarget{
+				ScrapeURL:  "http://url1.example.com",
+				Health:     health,
+				LastScrape: lastScrape,
+				Labels: model.LabelSet{
+					sourceLabelName: model.LabelValue(src),
+				},
+			},
+		}
+	}
+
+	targets := Targets{
+		target("src3", v1.HealthGood, now),
+		target("src1", v1.HealthBad, now.Add(-2*time.Minute)),
+		target("src2", v1.HealthGood, now.Add(-time.Minute)),
+	}
+
+	var tests = []struct {
+		policy   DedupPolicy
+		expected Target
+	}{
+		{DedupWorstHealth, targets[1]},
+		{DedupBestHealth, targets[2]},
+		{DedupMajority, targets[2]},
+		{DedupMostRecentScrape, targets[0]},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(string(tc.policy), func(t *testing.T) {
+			dedup := targets.Deduplicate(WithDedupPolicy(tc.policy))
+			require.Len(t, dedup, 1)
+
+			assert.Equal(t, tc.expected.Health, dedup[0].Health)
+			assert.Equal(t, tc.expected.LastScrape, dedup[0].LastScrape)
+			assert.Equal(t, model.LabelValue("src1,src2,src3"), dedup[0].Labels[sourceLabelName])
+			assert.Equal(t, model.LabelValue("src1=down,src2=up,src3=up"), dedup[0].Labels[healthConflictLabelName])
+		})
+	}
+
+	t.Run("targets with different dedup labels are not merged", func(t *testing.T) {
+		targets := Targets{
+			target("src1", v1.HealthGood, now),
+			target("src2", v1.HealthGood, now),
+		}
+		targets[0].Labels["env"] = "test"
+
+		assert.Len(t, targets.Deduplicate(), 1)
+		assert.Len(t, targets.Deduplicate(WithDedupLabels("env")), 2)
+	})
+}
+
+func TestTargetSortBy(t *testing.T) {
+	now := time.Now()
+
+	target := func(url string, health v1.HealthStatus, lastScrape time.Time, duration float64, team string) Target {
+		return Target{
+			ActiveTarget: v1.ActiveTarget{
+				ScrapeURL:          url,
+				Health:             health,
+				LastScrape:         lastScrape,
+				LastScrapeDuration: duration,
+				Labels: model.LabelSet{
+					"job":  "node",
+					"team": model.LabelValue(team),
+				},
+			},
+		}
+	}
+
+	targets := Targets{
+		target("http://a", v1.HealthGood, now.Add(-time.Minute), 0.5, "db"),
+		target("http://b", v1.HealthBad, now, 9.5, "infra"),
+		target("http://c", v1.HealthUnknown, now.Add(-time.Hour), 0.1, "app"),
+		target("http://d", v1.HealthGood, now.Add(-time.Second), 2, "db"),
+	}
+
+	urls := func(t Targets) []string {
+		s := []string{}
+		for i := range t {
+			s = append(s, t[i].ScrapeURL)
+		}
+
+		return s
+	}
+
+	tt := []struct {
+		name     string
+		keys     []string
+		reverse  bool
+		expected []string
+	}{
+		{"default", nil, false, []string{"http://a", "http://b", "http://c", "http://d"}},
+		{"reverse", nil, true, []string{"http://d", "http://c", "http://b", "http://a"}},
+		{"health", []string{"health"}, false, []string{"http://b", "http://c", "http://a", "http://d"}},
+		{"last scrape", []string{"last-scrape"}, false, []string{"http://b", "http://d", "http://a", "http://c"}},
+		{"slowest first", []string{"duration"}, true, []string{"http://b", "http://d", "http://a", "http://c"}},
+		{"label", []string{"label:team"}, false, []string{"http://c", "http://a", "http://d", "http://b"}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			keys := []TargetSortKey{}
+
+			for _, s := range tc.keys {
+				k, err := ParseTargetSortKey(s)
+				require.NoError(t, err)
+
+				keys = append(keys, k)
+			}
+
+			s := append(Targets{}, targets...)
+			s.SortBy(tc.reverse, keys...)
+			assert.Equal(t, tc.expected, urls(s))
+		})
+	}
+
+	t.Run("invalid sort keys must return an error", func(t *testing.T) {
+		for _, s := range []string{"foo", "label:", "label:1abc"} {
+			_, err := ParseTargetSortKey(s)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestTargetScrapeFilters(t *testing.T) {
+	now := time.Now()
+
+	targets := Targets{
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://fresh", LastScrape: now, LastScrapeDuration: 0.2}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://stale", LastScrape: now.Add(-5 * time.Minute), LastScrapeDuration: 0.2}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://slow", LastScrape: now, LastScrapeDuration: 7.5, LastError: "context deadline exceeded"}},
+	}
+
+	urls := func(t Targets) []string {
+		s := []string{}
+		for i := range t {
+			s = append(s, t[i].ScrapeURL)
+		}
+
+		return s
+	}
+
+	assert.Equal(t, []string{"http://stale"}, urls(targets.Filter(TargetByStaleFor(2*time.Minute))))
+	assert.Equal(t, []string{"http://slow"}, urls(targets.Filter(TargetBySlowerThan(5*time.Second))))
+	assert.Equal(t, []string{"http://slow"}, urls(targets.Filter(TargetByLastError(regexp.MustCompile("deadline")))))
 }
@@ -0,0 +1,115 @@
+package prometheus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	instanceLabelName = "instance"
+
+	scrapeSamplesScraped  = "scrape_samples_scraped"
+	scrapeSeriesAdded     = "scrape_series_added"
+	scrapeDurationSeconds = "scrape_duration_seconds"
+)
+
+// targetStatsQuery selects the scrape metrics of all targets with one query.
+const targetStatsQuery = `{__name__=~"` + scrapeSamplesScraped + "|" + scrapeSeriesAdded + "|" + scrapeDurationSeconds + `"}`
+
+// TargetStats are the sample counts and the duration of the last scrape of a target.
+type TargetStats struct {
+	SamplesScraped float64 `json:"samplesScraped"`
+	SeriesAdded    float64 `json:"seriesAdded"`
+	ScrapeDuration float64 `json:"scrapeDuration"`
+}
+
+// row returns the stats columns, which are empty for missing stats.
+func (s *TargetStats) row() []string {
+	if s == nil {
+		return []string{"", "", ""}
+	}
+
+	return []string{
+		strconv.FormatFloat(s.SamplesScraped, 'f', -1, 64),
+		strconv.FormatFloat(s.SeriesAdded, 'f', -1, 64),
+		(time.Duration(s.ScrapeDuration * float64(time.Second))).Round(time.Millisecond).String(),
+	}
+}
+
+// samples returns the samples scraped or -1 for missing stats.
+func (s *TargetStats) samples() float64 {
+	if s == nil {
+		return -1
+	}
+
+	return s.SamplesScraped
+}
+
+// seriesAdded returns the series added or -1 for missing stats.
+func (s *TargetStats) seriesAdded() float64 {
+	if s == nil {
+		return -1
+	}
+
+	return s.SeriesAdded
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// TargetStats requests the samples scraped, series added and scrape duration of the
+// last scrape of the targets with one instant query per server and sets the stats of
+// the targets. The values are joined by server, job and instance. Errors of single
+// servers are returned as warnings, an error is returned only if all servers fail.
+func (c *Client) TargetStats(ctx context.Context, targets Targets) (v1.Warnings, error) {
+	v, warnings, err := c.Query(ctx, targetStatsQuery, time.Now())
+	if err != nil {
+		return warnings, err
+	}
+
+	vector, _ := v.(model.Vector)
+	stats := map[string]*TargetStats{}
+
+	for _, s := range vector {
+		key := targetStatsKey(model.LabelSet(s.Metric))
+
+		st, ok := stats[key]
+		if !ok {
+			st = &TargetStats{}
+			stats[key] = st
+		}
+
+		switch s.Metric[model.MetricNameLabel] {
+		case scrapeSamplesScraped:
+			st.SamplesScraped = float64(s.Value)
+		case scrapeSeriesAdded:
+			st.SeriesAdded = float64(s.Value)
+		case scrapeDurationSeconds:
+			st.ScrapeDuration = float64(s.Value)
+		}
+	}
+
+	for i := range targets {
+		targets[i].withStats = true
+		targets[i].Stats = stats[targetStatsKey(targets[i].Labels)]
+	}
+
+	return warnings, nil
+}
+
+// targetStatsKey returns the key of the server, job and instance of the labels.
+func targetStatsKey(l model.LabelSet) string {
+	return string(l[sourceLabelName]) + "|" + string(l[jobLabelName]) + "|" + string(l[instanceLabelName])
+}
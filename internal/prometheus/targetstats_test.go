@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetStats(t *testing.T) {
+	queries := make(chan string, 10)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			fmt.Fprintln(w, `{"status":"success","data":{"activeTargets":[
+				{"labels":{"instance":"n1:9100","job":"node"},"scrapePool":"node","scrapeUrl":"http://n1:9100/metrics","health":"up"},
+				{"labels":{"instance":"n2:9100","job":"node"},"scrapePool":"node","scrapeUrl":"http://n2:9100/metrics","health":"up"},
+				{"labels":{"instance":"w1:80","job":"web"},"scrapePool":"web","scrapeUrl":"http://w1/metrics","health":"up"}]}}`)
+		case "/api/v1/query":
+			queries <- r.FormValue("query")
+			fmt.Fprintln(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"scrape_samples_scraped","instance":"n1:9100","job":"node"},"value":[1,"1200"]},
+				{"metric":{"__name__":"scrape_series_added","instance":"n1:9100","job":"node"},"value":[1,"3"]},
+				{"metric":{"__name__":"scrape_duration_seconds","instance":"n1:9100","job":"node"},"value":[1,"0.0423"]},
+				{"metric":{"__name__":"scrape_samples_scraped","instance":"w1:80","job":"web"},"value":[1,"150"]}]}}`)
+		}
+	}))
+	defer s.Close()
+
+	c, err := New([]string{s.URL}, WithRetries(0, 0))
+	require.NoError(t, err)
+
+	targets, _, err := c.Targets(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, targets, 3)
+
+	assert.Len(t, targets[0].Header(), 7, "the stats columns must not be shown without stats")
+
+	warnings, err := c.TargetStats(context.Background(), targets)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, targetStatsQuery, <-queries, "the stats must be requested with one query")
+
+	targets.SortBy(true, TargetSortBySamples)
+
+	require.NotNil(t, targets[0].Stats)
+	assert.Equal(t, "http://n1:9100/metrics", targets[0].ScrapeURL)
+	assert.Equal(t, TargetStats{SamplesScraped: 1200, SeriesAdded: 3, ScrapeDuration: 0.0423}, *targets[0].Stats)
+	assert.Equal(t, []string{"1200", "3", "42ms"}, targets[0].Row()[7:])
+
+	assert.Equal(t, "http://w1/metrics", targets[1].ScrapeURL)
+
+	assert.Nil(t, targets[2].Stats, "a target without values must not have stats")
+	assert.Equal(t, []string{"", "", ""}, targets[2].Row()[7:])
+	assert.Equal(t, []string{"SAMPLES", "SERIES_ADDED", "SCRAPE_DURATION"}, targets[2].Header()[7:])
+}
@@ -0,0 +1,183 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned if requests to a prometheus server are skipped because
+// of too many consecutive failures.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+const maxBackoff = 10 * time.Second
+
+// transport is a http.RoundTripper to a single prometheus server. It limits the
+// number of requests in flight to all servers, retries idempotent requests on
+// transient errors and skips requests while the circuit breaker is open.
+type transport struct {
+	next    http.RoundTripper
+	limit   chan struct{}
+	retries int
+	backoff time.Duration
+	breaker *breaker
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTrip(req)
+
+		if errors.Is(err, context.Canceled) {
+			t.breaker.release()
+			return resp, err
+		}
+
+		if !transient(resp, err) {
+			t.breaker.done(err == nil && resp.StatusCode < http.StatusInternalServerError)
+			return resp, err
+		}
+
+		if attempt >= t.retries || !idempotent(req) {
+			t.breaker.done(false)
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			if errors.Is(req.Context().Err(), context.Canceled) {
+				t.breaker.release()
+			} else {
+				t.breaker.done(false)
+			}
+
+			return nil, req.Context().Err()
+		case <-time.After(t.backoffFor(attempt)):
+		}
+	}
+}
+
+// roundTrip sends the request, if the number of requests in flight is below the limit.
+func (t *transport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.limit != nil {
+		select {
+		case t.limit <- struct{}{}:
+			defer func() { <-t.limit }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// backoffFor returns the exponential backoff with full jitter for the attempt.
+func (t *transport) backoffFor(attempt int) time.Duration {
+	d := t.backoff << uint(attempt)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(d) + 1)) //nolint:gosec // jitter does not need a secure random number
+}
+
+// transient returns true for connection errors and for responses of overloaded or
+// unavailable servers.
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func idempotent(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.Body == nil
+}
+
+// breaker is a circuit breaker. It opens after threshold consecutive failures and
+// allows a single trial request after the cooldown. A threshold of zero disables
+// the breaker.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *breaker) allow() error {
+	if b == nil || b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if time.Now().Before(b.openUntil) || b.trial {
+		return ErrCircuitOpen
+	}
+
+	b.trial = true
+
+	return nil
+}
+
+// release ends a trial request without a result.
+func (b *breaker) release() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+// done records the result of a request.
+func (b *breaker) done(success bool) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if success {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,138 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetries(t *testing.T) {
+	var requests int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintln(w, targets2)
+	}))
+	defer s.Close()
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		cli, err := New([]string{s.URL}, WithRetries(2, time.Millisecond))
+		require.NoError(t, err)
+
+		targets, _, err := cli.Targets(context.Background(), false)
+		require.NoError(t, err)
+		assert.Len(t, targets, 1)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("without retries the error is returned", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+
+		cli, err := New([]string{s.URL})
+		require.NoError(t, err)
+
+		_, _, err = cli.Targets(context.Background(), false)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	cli, err := New([]string{s.URL}, WithCircuitBreaker(2, time.Hour))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, _, err = cli.Targets(context.Background(), false)
+		require.Error(t, err)
+	}
+
+	_, _, err = cli.Targets(context.Background(), false)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests), "requests are skipped while the circuit breaker is open")
+
+	t.Run("server errors are failures, client errors are not", func(t *testing.T) {
+		status := int32(http.StatusInternalServerError)
+
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+		}))
+		defer s.Close()
+
+		cli, err := New([]string{s.URL}, WithCircuitBreaker(2, time.Hour))
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, _, err = cli.Targets(context.Background(), false)
+			require.Error(t, err)
+		}
+
+		_, _, err = cli.Targets(context.Background(), false)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+
+		atomic.StoreInt32(&status, http.StatusUnauthorized)
+
+		cli, err = New([]string{s.URL}, WithCircuitBreaker(2, time.Hour))
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, _, err = cli.Targets(context.Background(), false)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrCircuitOpen)
+		}
+	})
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var inFlight, max int32
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, targets2)
+	})
+
+	urls := []string{}
+
+	for i := 0; i < 4; i++ {
+		s := httptest.NewServer(handler)
+		defer s.Close()
+
+		urls = append(urls, s.URL)
+	}
+
+	cli, err := New(urls, WithMaxInFlight(1))
+	require.NoError(t, err)
+
+	targets, _, err := cli.Targets(context.Background(), false)
+	require.NoError(t, err)
+	assert.Len(t, targets, 4)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&max))
+}
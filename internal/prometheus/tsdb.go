@@ -0,0 +1,307 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// TSDBStatKind is the kind of a tsdb statistic.
+type TSDBStatKind string
+
+// All kinds of tsdb statistics.
+const (
+	TSDBHead            TSDBStatKind = "head"
+	TSDBSeries          TSDBStatKind = "series"
+	TSDBLabelValues     TSDBStatKind = "label-values"
+	TSDBMemory          TSDBStatKind = "memory"
+	TSDBLabelValuePairs TSDBStatKind = "label-value-pairs"
+)
+
+// tsdbStatKinds returns the kinds in the order of the report.
+func tsdbStatKinds() []TSDBStatKind {
+	return []TSDBStatKind{TSDBHead, TSDBSeries, TSDBLabelValues, TSDBMemory, TSDBLabelValuePairs}
+}
+
+// TSDBSnapshot are the tsdb statistics of the prometheus servers at a point in time.
+type TSDBSnapshot struct {
+	Time    time.Time                `json:"time"`
+	Servers map[string]v1.TSDBResult `json:"servers"`
+}
+
+// TSDB returns the tsdb statistics of all prometheus servers. Errors of single servers
+// are returned as warnings, an error is returned only if all servers fail.
+func (c *Client) TSDB(ctx context.Context) (*TSDBSnapshot, v1.Warnings, error) {
+	results, warnings, err := c.queryAll(ctx, func(ctx context.Context, client v1.API) (interface{}, v1.Warnings, error) {
+		r, err := client.TSDB(ctx)
+		return r, nil, err
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	s := &TSDBSnapshot{
+		Time:    time.Now(),
+		Servers: make(map[string]v1.TSDBResult, len(results)),
+	}
+
+	for _, r := range results {
+		s.Servers[r.server] = r.value.(v1.TSDBResult)
+	}
+
+	return s, warnings, nil
+}
+
+// LoadTSDBSnapshot loads a snapshot saved with Save.
+func LoadTSDBSnapshot(path string) (*TSDBSnapshot, error) {
+	b, err := ioutil.ReadFile(path) //nolint:gosec // the path is configured by the user
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TSDBSnapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Save writes the snapshot to a file.
+func (s *TSDBSnapshot) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// Stats merges the statistics of the prometheus servers. The values of all kinds but
+// label-values are summed up. The number of values of a label is the maximum of the
+// servers, because the servers share most values (e.g. of HA replicas). The stats are
+// sorted by kind and descending value.
+//
+// Note that prometheus returns only the top 10 of each kind except head, so the merged
+// values of the names not in the top 10 of every server are too low.
+func (s *TSDBSnapshot) Stats() TSDBStats {
+	merged := map[TSDBStatKind]map[string]*TSDBStat{}
+
+	add := func(kind TSDBStatKind, server, name string, value uint64) {
+		if merged[kind] == nil {
+			merged[kind] = map[string]*TSDBStat{}
+		}
+
+		st, ok := merged[kind][name]
+		if !ok {
+			st = &TSDBStat{Kind: kind, Name: name, Servers: map[string]uint64{}}
+			merged[kind][name] = st
+		}
+
+		st.Servers[server] = value
+
+		if kind == TSDBLabelValues {
+			if value > st.Value {
+				st.Value = value
+			}
+
+			return
+		}
+
+		st.Value += value
+	}
+
+	for server, r := range s.Servers {
+		add(TSDBHead, server, "series", uint64(r.HeadStats.NumSeries))
+		add(TSDBHead, server, "label-pairs", uint64(r.HeadStats.NumLabelPairs))
+		add(TSDBHead, server, "chunks", uint64(r.HeadStats.ChunkCount))
+
+		for _, kind := range []struct {
+			kind  TSDBStatKind
+			stats []v1.Stat
+		}{
+			{TSDBSeries, r.SeriesCountByMetricName},
+			{TSDBLabelValues, r.LabelValueCountByLabelName},
+			{TSDBMemory, r.MemoryInBytesByLabelName},
+			{TSDBLabelValuePairs, r.SeriesCountByLabelValuePair},
+		} {
+			for _, st := range kind.stats {
+				add(kind.kind, server, st.Name, st.Value)
+			}
+		}
+	}
+
+	stats := TSDBStats{}
+	kinds := tsdbStatKinds()
+
+	for _, kind := range kinds {
+		for _, st := range merged[kind] {
+			stats = append(stats, *st)
+		}
+	}
+
+	order := map[TSDBStatKind]int{}
+	for i, k := range kinds {
+		order[k] = i
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+
+		switch {
+		case a.Kind != b.Kind:
+			return order[a.Kind] < order[b.Kind]
+		case a.Value != b.Value:
+			return a.Value > b.Value
+		}
+
+		return a.Name < b.Name
+	})
+
+	return stats
+}
+
+// TSDBStat is a tsdb statistic of all prometheus servers.
+type TSDBStat struct {
+	Kind    TSDBStatKind      `json:"kind"`
+	Name    string            `json:"name"`
+	Value   uint64            `json:"value"`
+	Servers map[string]uint64 `json:"servers"`
+	// Previous is the value of the compared snapshot. It is nil if the stat is
+	// missing in the snapshot.
+	Previous *uint64 `json:"previous,omitempty"`
+	compared bool
+}
+
+// Growth returns the relative growth against the compared snapshot.
+func (s TSDBStat) Growth() float64 {
+	if s.Previous == nil || *s.Previous == 0 {
+		return 0
+	}
+
+	return (float64(s.Value) - float64(*s.Previous)) / float64(*s.Previous)
+}
+
+// Header represents a tsdb stat header.
+func (s TSDBStat) Header() []string {
+	if s.compared {
+		return []string{"KIND", "NAME", "VALUE", "PREVIOUS", "GROWTH", "SERVERS"}
+	}
+
+	return []string{"KIND", "NAME", "VALUE", "SERVERS"}
+}
+
+// Row represents a tsdb stat row.
+func (s TSDBStat) Row() []string {
+	servers := make([]string, 0, len(s.Servers))
+	for server := range s.Servers {
+		servers = append(servers, server)
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		if s.Servers[servers[i]] != s.Servers[servers[j]] {
+			return s.Servers[servers[i]] > s.Servers[servers[j]]
+		}
+
+		return servers[i] < servers[j]
+	})
+
+	for i, server := range servers {
+		servers[i] = fmt.Sprintf("%s=%d", server, s.Servers[server])
+	}
+
+	value := strconv.FormatUint(s.Value, 10)
+
+	if !s.compared {
+		return []string{string(s.Kind), s.Name, value, strings.Join(servers, ",")}
+	}
+
+	previous, growth := "", color.New(color.FgYellow).Sprint("new")
+
+	if s.Previous != nil {
+		previous = strconv.FormatUint(*s.Previous, 10)
+
+		col := color.New(color.FgGreen).SprintFunc()
+		if s.Value > *s.Previous {
+			col = color.New(color.FgRed).SprintFunc()
+		}
+
+		growth = col(fmt.Sprintf("%+.1f%%", s.Growth()*100))
+	}
+
+	return []string{string(s.Kind), s.Name, value, previous, growth, strings.Join(servers, ",")}
+}
+
+// TSDBStats is a list of tsdb statistics.
+type TSDBStats []TSDBStat
+
+// ByKind returns the stats of the kinds.
+func (s TSDBStats) ByKind(kinds ...TSDBStatKind) TSDBStats {
+	result := TSDBStats{}
+
+	for _, st := range s {
+		for _, k := range kinds {
+			if st.Kind == k {
+				result = append(result, st)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// Top returns the first n stats of every kind. The stats must be sorted by kind.
+func (s TSDBStats) Top(n int) TSDBStats {
+	result := TSDBStats{}
+	count := map[TSDBStatKind]int{}
+
+	for _, st := range s {
+		if count[st.Kind] >= n {
+			continue
+		}
+
+		count[st.Kind]++
+
+		result = append(result, st)
+	}
+
+	return result
+}
+
+// Compare sets the previous values of the stats to the values of the previous stats.
+func (s TSDBStats) Compare(previous TSDBStats) {
+	index := map[TSDBStatKind]map[string]uint64{}
+
+	for _, st := range previous {
+		if index[st.Kind] == nil {
+			index[st.Kind] = map[string]uint64{}
+		}
+
+		index[st.Kind][st.Name] = st.Value
+	}
+
+	for i := range s {
+		s[i].compared = true
+		s[i].Previous = nil
+
+		if v, ok := index[s[i].Kind][s[i].Name]; ok {
+			v := v
+			s[i].Previous = &v
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTSDB(t *testing.T) {
+	newServer := func(series int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"status":"success","data":{
+				"headStats":{"numSeries":%d,"numLabelPairs":200,"chunkCount":3000},
+				"seriesCountByMetricName":[{"name":"http_requests_total","value":%d},{"name":"up","value":10}],
+				"labelValueCountByLabelName":[{"name":"path","value":%d}],
+				"memoryInBytesByLabelName":[{"name":"path","value":4000}],
+				"seriesCountByLabelValuePair":[{"name":"job=web","value":600}]}}`, series, series/2, series/4)
+		}))
+	}
+
+	s1 := newServer(1000)
+	defer s1.Close()
+
+	s2 := newServer(2000)
+	defer s2.Close()
+
+	server2 := "127.0.0.1:" + port(t, s2.URL)
+
+	c, err := New([]string{s1.URL, s2.URL}, WithRetries(0, 0))
+	require.NoError(t, err)
+
+	snapshot, warnings, err := c.TSDB(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	require.Len(t, snapshot.Servers, 2)
+
+	stats := snapshot.Stats()
+
+	series := stats.ByKind(TSDBSeries)
+	require.Len(t, series, 2)
+	assert.Equal(t, "http_requests_total", series[0].Name)
+	assert.Equal(t, uint64(1500), series[0].Value, "the series must be summed up")
+	assert.Equal(t, uint64(1000), series[0].Servers[server2])
+
+	labelValues := stats.ByKind(TSDBLabelValues)
+	require.Len(t, labelValues, 1)
+	assert.Equal(t, uint64(500), labelValues[0].Value, "the label values must be the maximum")
+
+	head := stats.ByKind(TSDBHead)
+	require.Len(t, head, 3)
+	assert.Equal(t, TSDBStat{Kind: TSDBHead, Name: "series", Value: 3000, Servers: head[1].Servers}, head[1])
+
+	top := stats.Top(1)
+	require.Len(t, top, 5)
+	assert.Equal(t, TSDBHead, top[0].Kind)
+	assert.Equal(t, TSDBLabelValuePairs, top[4].Kind)
+
+	t.Run("compare with a saved snapshot", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshots", "tsdb.json")
+
+		previous := &TSDBSnapshot{Servers: map[string]v1.TSDBResult{
+			server2: {SeriesCountByMetricName: []v1.Stat{{Name: "http_requests_total", Value: 500}}},
+		}}
+		require.NoError(t, previous.Save(path))
+
+		loaded, err := LoadTSDBSnapshot(path)
+		require.NoError(t, err)
+
+		series := snapshot.Stats().ByKind(TSDBSeries)
+		series.Compare(loaded.Stats())
+
+		require.NotNil(t, series[0].Previous)
+		assert.Equal(t, uint64(500), *series[0].Previous)
+		assert.InDelta(t, 2.0, series[0].Growth(), 0.001)
+		assert.Contains(t, series[0].Row()[4], "+200.0%")
+
+		assert.Nil(t, series[1].Previous, "up is missing in the snapshot")
+		assert.Equal(t, []string{"KIND", "NAME", "VALUE", "PREVIOUS", "GROWTH", "SERVERS"}, series[1].Header())
+	})
+}
@@ -0,0 +1,367 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/rivo/tview"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// tab is a tab of the UI showing a list of items.
+type tab interface {
+	// title returns the title of the tab.
+	title() string
+	// header returns the column names.
+	header() []string
+	// load returns a new tab with the items of the prometheus servers.
+	load(ctx context.Context, c *prometheus.Client) (tab, error)
+	// items returns the filtered items.
+	items(f filter) []item
+	// page returns the path of the page of the prometheus UI for the tab.
+	page() string
+}
+
+// filter is the incremental filter of the UI. Items match if the regular expression
+// matches any of the server, job, name or scrape url and the selector matches the labels.
+type filter struct {
+	re  *regexp.Regexp
+	sel labels.Selector
+}
+
+// item is a row of a tab.
+type item struct {
+	cells  []cell
+	server string
+	// link is the URL opened for the item, e.g. the scrape url of a target.
+	link   string
+	detail string
+}
+
+// cell is a table cell. Cells are sorted by value if numeric is true, otherwise
+// by text.
+type cell struct {
+	text    string
+	value   float64
+	numeric bool
+	color   tcell.Color
+}
+
+func textCell(s string) cell {
+	return cell{text: s, color: tcell.ColorDefault}
+}
+
+// ageCell returns a cell with the time since t.
+func ageCell(t time.Time) cell {
+	d := time.Since(t)
+	if t.IsZero() {
+		return cell{text: "-", numeric: true, color: tcell.ColorDefault}
+	}
+
+	return cell{text: d.Round(time.Second).String(), value: d.Seconds(), numeric: true, color: tcell.ColorDefault}
+}
+
+func healthCell(s string, good, bad string) cell {
+	c := textCell(s)
+
+	switch s {
+	case good:
+		c.color = tcell.ColorGreen
+	case bad:
+		c.color = tcell.ColorRed
+	default:
+		c.color = tcell.ColorYellow
+	}
+
+	return c
+}
+
+// sortItems sorts the items by the column. Items with equal values keep their order.
+func sortItems(items []item, column int, reverse bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].cells[column], items[j].cells[column]
+		if reverse {
+			a, b = b, a
+		}
+
+		if a.numeric && b.numeric {
+			return a.value < b.value
+		}
+
+		return a.text < b.text
+	})
+}
+
+type targetsTab struct {
+	targets prometheus.Targets
+}
+
+func (t *targetsTab) title() string {
+	return "Targets"
+}
+
+func (t *targetsTab) header() []string {
+	return []string{"SERVER", "JOB", "SCRAPE_URL", "LAST_SCRAPE", "DURATION", "HEALTH"}
+}
+
+func (t *targetsTab) page() string {
+	return "/targets"
+}
+
+func (t *targetsTab) load(ctx context.Context, c *prometheus.Client) (tab, error) {
+	// failing servers are shown as scraper targets
+	targets, _, err := c.Targets(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	targets.Sort()
+
+	return &targetsTab{targets: targets}, nil
+}
+
+func (t *targetsTab) items(f filter) []item {
+	targets := t.targets
+
+	if f.re != nil {
+		targets = targets.Filter(func(target prometheus.Target) bool {
+			return prometheus.TargetByServer(f.re)(target) || prometheus.TargetByJob(f.re)(target) ||
+				prometheus.TargetByScrapeURL(f.re)(target)
+		})
+	}
+
+	if f.sel != nil {
+		targets = targets.Filter(prometheus.TargetBySelector(f.sel))
+	}
+
+	items := make([]item, 0, len(targets))
+
+	for i := range targets {
+		target := targets[i]
+		duration := time.Duration(target.LastScrapeDuration * float64(time.Second))
+
+		items = append(items, item{
+			cells: []cell{
+				textCell(target.Server()),
+				textCell(target.Job()),
+				textCell(target.ScrapeURL),
+				ageCell(target.LastScrape),
+				{text: duration.Round(time.Millisecond).String(), value: duration.Seconds(), numeric: true, color: tcell.ColorDefault},
+				healthCell(string(target.Health), string(v1.HealthGood), string(v1.HealthBad)),
+			},
+			server: target.Server(),
+			link:   target.ScrapeURL,
+			detail: targetDetail(&target),
+		})
+	}
+
+	return items
+}
+
+func targetDetail(t *prometheus.Target) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[::b]Scrape URL:[::-] %s\n", t.ScrapeURL)
+	fmt.Fprintf(&b, "[::b]Scrape pool:[::-] %s\n", t.ScrapePool)
+	fmt.Fprintf(&b, "[::b]Health:[::-] %s\n", t.Health)
+	fmt.Fprintf(&b, "[::b]Last scrape:[::-] %s\n", t.LastScrape.Format(time.RFC3339))
+
+	if t.LastError != "" {
+		fmt.Fprintf(&b, "[::b]Last error:[::-] [red]%s[-]\n", tview.Escape(t.LastError))
+	}
+
+	writeLabels(&b, "Labels", t.Labels)
+
+	discovered := model.LabelSet{}
+	for k, v := range t.DiscoveredLabels {
+		discovered[model.LabelName(k)] = model.LabelValue(v)
+	}
+
+	writeLabels(&b, "Discovered labels", discovered)
+
+	return b.String()
+}
+
+type alertsTab struct {
+	alerts prometheus.Alerts
+}
+
+func (a *alertsTab) title() string {
+	return "Alerts"
+}
+
+func (a *alertsTab) header() []string {
+	return []string{"SERVER", "JOB", "ALERT_NAME", "SINCE", "STATE"}
+}
+
+func (a *alertsTab) page() string {
+	return "/alerts"
+}
+
+func (a *alertsTab) load(ctx context.Context, c *prometheus.Client) (tab, error) {
+	// the alerts of failing servers are missing, like the targets of failing servers
+	alerts, _, err := c.Alerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &alertsTab{alerts: alerts}, nil
+}
+
+func (a *alertsTab) items(f filter) []item {
+	alerts := a.alerts
+
+	if f.re != nil {
+		alerts = alerts.Filter(func(alert prometheus.Alert) bool {
+			return prometheus.AlertByServer(f.re)(alert) || prometheus.AlertByJob(f.re)(alert) ||
+				prometheus.AlertByName(f.re)(alert)
+		})
+	}
+
+	if f.sel != nil {
+		alerts = alerts.Filter(prometheus.AlertBySelector(f.sel))
+	}
+
+	items := make([]item, 0, len(alerts))
+
+	for _, alert := range alerts {
+		server := alert.Server()
+
+		items = append(items, item{
+			cells: []cell{
+				textCell(server),
+				textCell(alert.Job()),
+				textCell(alert.Name()),
+				ageCell(alert.ActiveAt),
+				healthCell(string(alert.State), "", string(v1.AlertStateFiring)),
+			},
+			server: server,
+			detail: alertDetail(alert),
+		})
+	}
+
+	return items
+}
+
+func alertDetail(a prometheus.Alert) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[::b]State:[::-] %s\n", a.State)
+	fmt.Fprintf(&b, "[::b]Active since:[::-] %s\n", a.ActiveAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "[::b]Value:[::-] %s\n", a.Value)
+
+	writeLabels(&b, "Labels", a.Labels)
+	writeLabels(&b, "Annotations", a.Annotations)
+
+	return b.String()
+}
+
+type rulesTab struct {
+	rules prometheus.Rules
+}
+
+func (r *rulesTab) title() string {
+	return "Rules"
+}
+
+func (r *rulesTab) header() []string {
+	return []string{"SERVER", "GROUP", "NAME", "TYPE", "LAST_EVALUATION", "HEALTH"}
+}
+
+func (r *rulesTab) page() string {
+	return "/rules"
+}
+
+func (r *rulesTab) load(ctx context.Context, c *prometheus.Client) (tab, error) {
+	rules, err := c.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules.Sort()
+
+	return &rulesTab{rules: rules}, nil
+}
+
+func (r *rulesTab) items(f filter) []item {
+	rules := r.rules
+
+	if f.re != nil {
+		rules = rules.Filter(func(rule prometheus.Rule) bool {
+			return prometheus.RuleByServer(f.re)(rule) || prometheus.RuleByGroup(f.re)(rule) ||
+				prometheus.RuleByName(f.re)(rule)
+		})
+	}
+
+	if f.sel != nil {
+		rules = rules.Filter(prometheus.RuleBySelector(f.sel))
+	}
+
+	items := make([]item, 0, len(rules))
+
+	for _, rule := range rules {
+		items = append(items, item{
+			cells: []cell{
+				textCell(rule.Server()),
+				textCell(rule.Group),
+				textCell(rule.Name),
+				textCell(string(rule.Type)),
+				ageCell(rule.LastEvaluation),
+				healthCell(string(rule.Health), v1.RuleHealthGood, v1.RuleHealthBad),
+			},
+			server: rule.Server(),
+			detail: ruleDetail(&rule),
+		})
+	}
+
+	return items
+}
+
+func ruleDetail(r *prometheus.Rule) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[::b]Query:[::-] %s\n", tview.Escape(r.Query))
+	fmt.Fprintf(&b, "[::b]File:[::-] %s\n", r.File)
+	fmt.Fprintf(&b, "[::b]Health:[::-] %s\n", r.Health)
+
+	if r.State != "" {
+		fmt.Fprintf(&b, "[::b]State:[::-] %s\n", r.State)
+	}
+
+	if r.LastError != "" {
+		fmt.Fprintf(&b, "[::b]Last error:[::-] [red]%s[-]\n", tview.Escape(r.LastError))
+	}
+
+	writeLabels(&b, "Labels", r.Labels)
+	writeLabels(&b, "Annotations", r.Annotations)
+
+	return b.String()
+}
+
+// writeLabels writes the sorted labels.
+func writeLabels(b *strings.Builder, title string, ls model.LabelSet) {
+	if len(ls) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(ls))
+	for name := range ls {
+		names = append(names, string(name))
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "\n[::b]%s:[::-]\n", title)
+
+	for _, name := range names {
+		fmt.Fprintf(b, "  %s=%q\n", name, tview.Escape(string(ls[model.LabelName(name)])))
+	}
+}
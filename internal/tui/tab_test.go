@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestTargetsTabItems(t *testing.T) {
+	tab := &targetsTab{
+		targets: prometheus.Targets{
+			newTarget("node", "http://a:9100/metrics", "infra"),
+			newTarget("node", "http://b:9100/metrics", "db"),
+			newTarget("mysql", "http://b:9104/metrics", "db"),
+		},
+	}
+
+	tt := []struct {
+		name     string
+		f        filter
+		expected []string
+	}{
+		{"no filter", filter{}, []string{"http://a:9100/metrics", "http://b:9100/metrics", "http://b:9104/metrics"}},
+		{"regex matches job or scrape url", filter{re: regexp.MustCompile("mysql|a:9100")}, []string{"http://a:9100/metrics", "http://b:9104/metrics"}},
+		{"selector", filter{sel: labels.SelectorFromSet(labels.Set{"team": "db"})}, []string{"http://b:9100/metrics", "http://b:9104/metrics"}},
+		{"regex and selector", filter{re: regexp.MustCompile("node"), sel: labels.SelectorFromSet(labels.Set{"team": "db"})}, []string{"http://b:9100/metrics"}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			links := []string{}
+			for _, i := range tab.items(tc.f) {
+				links = append(links, i.link)
+			}
+
+			assert.Equal(t, tc.expected, links)
+		})
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	items := []item{
+		{cells: []cell{textCell("b"), {text: "10s", value: 10, numeric: true}}},
+		{cells: []cell{textCell("a"), {text: "9s", value: 9, numeric: true}}},
+		{cells: []cell{textCell("c"), {text: "1m0s", value: 60, numeric: true}}},
+	}
+
+	first := func() []string {
+		s := []string{}
+		for _, i := range items {
+			s = append(s, i.cells[0].text)
+		}
+
+		return s
+	}
+
+	sortItems(items, 0, false)
+	assert.Equal(t, []string{"a", "b", "c"}, first())
+
+	sortItems(items, 1, false)
+	assert.Equal(t, []string{"a", "b", "c"}, first(), "numeric columns must be sorted by value")
+
+	sortItems(items, 1, true)
+	assert.Equal(t, []string{"c", "b", "a"}, first())
+}
+
+func newTarget(job, scrapeURL, team string) prometheus.Target {
+	return prometheus.Target{
+		ActiveTarget: v1.ActiveTarget{
+			ScrapePool: job,
+			ScrapeURL:  scrapeURL,
+			Labels: model.LabelSet{
+				"job":  model.LabelValue(job),
+				"team": model.LabelValue(team),
+			},
+			Health: v1.HealthGood,
+		},
+	}
+}
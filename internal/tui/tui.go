@@ -0,0 +1,421 @@
+// Package tui is an interactive terminal UI for the targets, alerts and rules of
+// all prometheus servers.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/rivo/tview"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const help = "[::b]1-3[::-] tab  [::b]/[::-] filter  [::b]l[::-] selector  [::b]esc[::-] clear  " +
+	"[::b]s/S[::-] sort column  [::b]r[::-] reverse  [::b]o[::-] open  [::b]p[::-] prometheus  [::b]R[::-] refresh  [::b]q[::-] quit"
+
+// UI is the terminal UI.
+type UI struct {
+	client   *prometheus.Client
+	interval time.Duration
+	timeout  time.Duration
+
+	app    *tview.Application
+	runes  map[rune]func()
+	tabs   *tview.TextView
+	table  *tview.Table
+	detail *tview.TextView
+	status *tview.TextView
+	input  *tview.InputField
+	layout *tview.Flex
+
+	// mu protects the fields below, they are modified by the refresh goroutine.
+	mu      sync.Mutex
+	views   []tab
+	current int
+	column  int
+	reverse bool
+	filter  filter
+	shown   []item
+	updated time.Time
+	err     error
+}
+
+// New creates a new terminal UI. The data is refreshed every interval.
+func New(client *prometheus.Client, interval, timeout time.Duration) *UI {
+	u := &UI{
+		client:   client,
+		interval: interval,
+		timeout:  timeout,
+		app:      tview.NewApplication(),
+		tabs:     tview.NewTextView().SetDynamicColors(true),
+		table:    tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		detail:   tview.NewTextView().SetDynamicColors(true).SetWrap(true),
+		status:   tview.NewTextView().SetDynamicColors(true),
+		input:    tview.NewInputField(),
+		views:    []tab{&targetsTab{}, &alertsTab{}, &rulesTab{}},
+		column:   -1,
+	}
+
+	u.runes = u.runeHandlers()
+
+	u.detail.SetBorder(true).SetTitle(" Details ")
+	u.table.SetBorder(true)
+	u.table.SetSelectionChangedFunc(func(row, column int) {
+		u.showDetail(row)
+	})
+
+	u.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.tabs, 1, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(u.table, 0, 3, true).
+			AddItem(u.detail, 0, 2, false), 0, 1, true).
+		AddItem(u.status, 1, 0, false)
+
+	u.app.SetRoot(u.layout, true).SetInputCapture(u.keys)
+
+	return u
+}
+
+// Run runs the UI until the user quits.
+func (u *UI) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go u.refreshLoop(ctx)
+
+	u.render()
+
+	return u.app.Run()
+}
+
+// refreshLoop refreshes the current tab in the configured interval.
+func (u *UI) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		u.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh loads the current tab.
+func (u *UI) refresh(ctx context.Context) {
+	u.mu.Lock()
+	current := u.current
+	t := u.views[current]
+	u.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	loaded, err := t.load(ctx, u.client)
+
+	u.mu.Lock()
+	u.err = err
+
+	if err == nil {
+		u.views[current] = loaded
+		u.updated = time.Now()
+	}
+	u.mu.Unlock()
+
+	u.app.QueueUpdateDraw(u.render)
+}
+
+// keys handles the key events of the table.
+func (u *UI) keys(event *tcell.EventKey) *tcell.EventKey {
+	if u.app.GetFocus() == u.input {
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		u.clearFilter()
+		return nil
+	case tcell.KeyTab:
+		u.selectTab(-1)
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+
+	handle, ok := u.runes[event.Rune()]
+	if !ok {
+		return event
+	}
+
+	handle()
+
+	return nil
+}
+
+// runeHandlers returns the handlers of the rune keys.
+func (u *UI) runeHandlers() map[rune]func() {
+	return map[rune]func(){
+		'q': u.app.Stop,
+		'1': func() { u.selectTab(0) },
+		'2': func() { u.selectTab(1) },
+		'3': func() { u.selectTab(2) },
+		'/': u.editFilter,
+		'l': u.editSelector,
+		's': func() { u.sortColumn(1) },
+		'S': func() { u.sortColumn(-1) },
+		'r': u.toggleReverse,
+		'o': func() { u.open(func(i item) string { return i.link }) },
+		'p': func() { u.open(u.prometheusLink) },
+		'R': func() { go u.refresh(context.Background()) },
+	}
+}
+
+// clearFilter removes the filter and the selector.
+func (u *UI) clearFilter() {
+	u.mu.Lock()
+	u.filter = filter{}
+	u.mu.Unlock()
+	u.render()
+}
+
+// editFilter edits the regex filter.
+func (u *UI) editFilter() {
+	u.edit("Filter (regex): ", func(s string) error {
+		if s == "" {
+			u.filter.re = nil
+			return nil
+		}
+
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+
+		u.filter.re = re
+
+		return nil
+	})
+}
+
+// editSelector edits the label selector.
+func (u *UI) editSelector() {
+	u.edit("Selector: ", func(s string) error {
+		if s == "" {
+			u.filter.sel = nil
+			return nil
+		}
+
+		sel, err := labels.Parse(s)
+		if err != nil {
+			return err
+		}
+
+		u.filter.sel = sel
+
+		return nil
+	})
+}
+
+// sortColumn selects the next (step 1) or previous (step -1) sort column. Column -1
+// is the default order of the tab.
+func (u *UI) sortColumn(step int) {
+	u.mu.Lock()
+	n := len(u.views[u.current].header()) + 1
+	u.column = (u.column+1+step+n)%n - 1
+	u.mu.Unlock()
+	u.render()
+}
+
+// toggleReverse reverses the sort order.
+func (u *UI) toggleReverse() {
+	u.mu.Lock()
+	u.reverse = !u.reverse
+	u.mu.Unlock()
+	u.render()
+}
+
+// prometheusLink returns the link to the page of the current tab on the prometheus
+// server of the item.
+func (u *UI) prometheusLink(i item) string {
+	base := u.client.ServerURL(i.server)
+	if base == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(base, "/") + u.views[u.current].page()
+}
+
+// selectTab selects the tab i or the next tab if i is negative.
+func (u *UI) selectTab(i int) {
+	u.mu.Lock()
+	if i < 0 {
+		i = (u.current + 1) % len(u.views)
+	}
+
+	changed := i != u.current
+	u.current = i
+	u.column = -1
+	u.reverse = false
+	u.mu.Unlock()
+
+	u.table.Select(1, 0)
+	u.render()
+
+	if changed {
+		go u.refresh(context.Background())
+	}
+}
+
+// edit shows the input field in the status line. The filter is applied on every change
+// and the field is red while the input is invalid.
+func (u *UI) edit(label string, apply func(string) error) {
+	u.input.SetLabel(label).SetText("").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetChangedFunc(func(text string) {
+			u.mu.Lock()
+			err := apply(text)
+			u.mu.Unlock()
+
+			if err != nil {
+				u.input.SetFieldTextColor(tcell.ColorRed)
+				return
+			}
+
+			u.input.SetFieldTextColor(tcell.ColorDefault)
+			u.render()
+		}).
+		SetDoneFunc(func(tcell.Key) {
+			u.layout.RemoveItem(u.input)
+			u.layout.AddItem(u.status, 1, 0, false)
+			u.app.SetFocus(u.table)
+			u.render()
+		})
+
+	u.layout.RemoveItem(u.status)
+	u.layout.AddItem(u.input, 1, 0, true)
+	u.app.SetFocus(u.input)
+}
+
+// open opens the URL of the selected item in the browser.
+func (u *UI) open(link func(item) string) {
+	row, _ := u.table.GetSelection()
+
+	u.mu.Lock()
+	var target string
+	if row > 0 && row <= len(u.shown) {
+		target = link(u.shown[row-1])
+	}
+	u.mu.Unlock()
+
+	if target == "" {
+		return
+	}
+
+	if err := openBrowser(target); err != nil {
+		u.mu.Lock()
+		u.err = err
+		u.mu.Unlock()
+		u.render()
+	}
+}
+
+// render draws the current tab. It must be called from the UI goroutine.
+func (u *UI) render() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	t := u.views[u.current]
+
+	titles := make([]string, 0, len(u.views))
+
+	for i, v := range u.views {
+		if i == u.current {
+			titles = append(titles, fmt.Sprintf("[black:white] %d %s [-:-]", i+1, v.title()))
+			continue
+		}
+
+		titles = append(titles, fmt.Sprintf(" %d %s ", i+1, v.title()))
+	}
+
+	u.tabs.SetText(strings.Join(titles, " "))
+
+	u.shown = t.items(u.filter)
+	if u.column >= 0 {
+		sortItems(u.shown, u.column, u.reverse)
+	}
+
+	u.table.Clear()
+
+	for col, name := range t.header() {
+		if col == u.column {
+			name += map[bool]string{false: " ▲", true: " ▼"}[u.reverse]
+		}
+
+		u.table.SetCell(0, col, tview.NewTableCell(name).SetSelectable(false).SetAttributes(tcell.AttrBold))
+	}
+
+	for row, i := range u.shown {
+		for col, c := range i.cells {
+			u.table.SetCell(row+1, col, tview.NewTableCell(tview.Escape(c.text)).SetTextColor(c.color))
+		}
+	}
+
+	u.table.SetTitle(fmt.Sprintf(" %s (%d) ", t.title(), len(u.shown)))
+
+	status := help
+	if u.err != nil {
+		status = fmt.Sprintf("[red]%s[-]", tview.Escape(u.err.Error()))
+	} else if !u.updated.IsZero() {
+		status = fmt.Sprintf("updated %s  %s", u.updated.Format("15:04:05"), help)
+	}
+
+	u.status.SetText(status)
+
+	row, _ := u.table.GetSelection()
+	u.detailText(row)
+}
+
+// showDetail shows the details of the selected row.
+func (u *UI) showDetail(row int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.detailText(row)
+}
+
+func (u *UI) detailText(row int) {
+	if row < 1 || row > len(u.shown) {
+		u.detail.SetText("")
+		return
+	}
+
+	u.detail.SetText(u.shown[row-1].detail).ScrollToBeginning()
+}
+
+// openBrowser opens the URL in the default browser.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+
+	return cmd.Start()
+}
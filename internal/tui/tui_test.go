@@ -0,0 +1,22 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortColumn(t *testing.T) {
+	u := New(nil, time.Minute, time.Second)
+	n := len(u.views[u.current].header())
+
+	u.sortColumn(-1)
+	assert.Equal(t, n-1, u.column, "the previous column of the default order must be the last column")
+
+	u.sortColumn(1)
+	assert.Equal(t, -1, u.column, "the next column of the last column must be the default order")
+
+	u.sortColumn(1)
+	assert.Equal(t, 0, u.column)
+}
@@ -1,9 +1,11 @@
 package web
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -12,25 +14,92 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	defaultTimeout        = 20 * time.Second
+	defaultEventsInterval = 30 * time.Second
+)
+
 // API represents the server API.
 type API struct {
 	router        chi.Router
+	srv           *http.Server
 	reactApp      http.FileSystem
 	urlPathPrefix string
 	listenAddr    string
-	deduplicate   bool
 	l             *zap.SugaredLogger
-	cli           *prometheus.Client
-	timeout       time.Duration
+	events        *broker
+	metrics       *metrics
+
+	reloadMu sync.Mutex
+
+	mu  sync.RWMutex
+	cfg config
 }
 
-// New initializes the API.
-func New(l *zap.SugaredLogger, timeout time.Duration, deduplicate bool, urls ...string) (*API, error) {
-	client, err := prometheus.New(urls...)
-	if err != nil {
-		return nil, err
+// config is the part of the API configuration that can be reloaded.
+type config struct {
+	cli            *prometheus.Client
+	timeout        time.Duration
+	deduplicate    bool
+	replicaLabels  []string
+	dedupOpts      []prometheus.DedupOption
+	eventsInterval time.Duration
+	reloader       func() error
+	lifecycle      bool
+}
+
+// Option is a functional option to configure the API.
+type Option func(*config)
+
+// WithTimeout sets the maximum duration of a request to the API.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithDeduplicate enables the deduplication of targets and alerts. The replicaLabels
+// are ignored on alert deduplication.
+func WithDeduplicate(deduplicate bool, replicaLabels ...string) Option {
+	return func(c *config) {
+		c.deduplicate = deduplicate
+		c.replicaLabels = replicaLabels
 	}
+}
 
+// WithTargetDedupOptions sets the options for the deduplication of targets.
+func WithTargetDedupOptions(opts ...prometheus.DedupOption) Option {
+	return func(c *config) {
+		c.dedupOpts = opts
+	}
+}
+
+// WithEventsInterval sets the interval in which targets and alerts are
+// compared to compute the change events. It cannot be changed on reload.
+func WithEventsInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.eventsInterval = d
+	}
+}
+
+// WithReloader sets the function that is called on a reload. It has to
+// re-read the configuration and call Apply.
+func WithReloader(f func() error) Option {
+	return func(c *config) {
+		c.reloader = f
+	}
+}
+
+// WithLifecycle enables the reload of the configuration with a POST or PUT request
+// to /-/reload.
+func WithLifecycle(enabled bool) Option {
+	return func(c *config) {
+		c.lifecycle = enabled
+	}
+}
+
+// New initializes the API.
+func New(l *zap.SugaredLogger, client *prometheus.Client, opts ...Option) (*API, error) {
 	react, err := ui.ReactApp()
 	if err != nil {
 		return nil, err
@@ -38,34 +107,117 @@ func New(l *zap.SugaredLogger, timeout time.Duration, deduplicate bool, urls ...
 
 	a := API{
 		l:             l,
-		timeout:       timeout,
-		deduplicate:   deduplicate,
 		reactApp:      react,
 		listenAddr:    ":8080",
 		urlPathPrefix: "/",
-		cli:           client,
+		metrics:       newMetrics(),
+		cfg: config{
+			timeout:        defaultTimeout,
+			eventsInterval: defaultEventsInterval,
+		},
 	}
 
 	if !strings.HasPrefix(a.urlPathPrefix, "/") {
 		return nil, errors.New("url prefix must start with '/'")
 	}
 
+	if err := a.Apply(client, opts...); err != nil {
+		return nil, err
+	}
+
+	a.events = newBroker(l, a.eventTargets, a.eventAlerts, a.config().eventsInterval)
+
 	r := chi.NewRouter()
 	a.router = r
+	a.srv = &http.Server{
+		Addr:    a.listenAddr,
+		Handler: r,
+	}
 
 	return &a, nil
 }
 
-// Start starts the server.
+// Apply validates and atomically swaps the client and the configuration. Options
+// that are not set keep their current value.
+func (a *API) Apply(client *prometheus.Client, opts ...Option) error {
+	if client == nil {
+		return errors.New("no prometheus client configured")
+	}
+
+	cfg := a.config()
+	cfg.cli = client
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.eventsInterval <= 0 {
+		return errors.New("events interval must be positive")
+	}
+
+	a.mu.Lock()
+	a.cfg = cfg
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Reload calls the configured reloader. If it fails, the current configuration
+// stays active. The result is reported in the metrics.
+func (a *API) Reload() error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	reloader := a.config().reloader
+	if reloader == nil {
+		return errors.New("reload is not supported")
+	}
+
+	if err := reloader(); err != nil {
+		a.metrics.reloadFailed()
+		a.l.Errorw("failed to reload configuration", "err", err)
+
+		return err
+	}
+
+	a.metrics.reloadSucceeded()
+	a.l.Infow("configuration reloaded")
+
+	return nil
+}
+
+// Client returns the current prometheus client.
+func (a *API) Client() *prometheus.Client {
+	return a.config().cli
+}
+
+// config returns the current configuration.
+func (a *API) config() config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.cfg
+}
+
+// Start starts the server. It returns nil after a Shutdown.
 func (a *API) Start() error {
 	if err := a.routes(); err != nil {
 		return err
 	}
 
-	httpSrv := &http.Server{
-		Addr:    ":8080",
-		Handler: a.router,
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go a.events.run(ctx)
+
+	if err := a.srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
 
-	return httpSrv.ListenAndServe()
+	return nil
+}
+
+// Shutdown gracefully stops the server.
+func (a *API) Shutdown(ctx context.Context) error {
+	return a.srv.Shutdown(ctx)
 }
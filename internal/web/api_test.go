@@ -0,0 +1,65 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReload(t *testing.T) {
+	c1, err := prometheus.New([]string{"http://prometheus101.example.com:9090"})
+	require.NoError(t, err)
+
+	c2, err := prometheus.New([]string{"http://prometheus102.example.com:9090"})
+	require.NoError(t, err)
+
+	var reloadErr error
+
+	var a *API
+
+	a, err = New(zap.NewNop().Sugar(), c1, WithDeduplicate(true), WithReloader(func() error {
+		if reloadErr != nil {
+			return reloadErr
+		}
+
+		return a.Apply(c2, WithDeduplicate(false))
+	}))
+	require.NoError(t, err)
+
+	t.Run("a failed reload keeps the current configuration", func(t *testing.T) {
+		reloadErr = errors.New("invalid config")
+
+		assert.Error(t, a.Reload())
+		assert.Equal(t, c1, a.Client())
+		assert.True(t, a.config().deduplicate)
+		assert.Equal(t, 0.0, testutil.ToFloat64(a.metrics.reloadSuccessful))
+	})
+
+	t.Run("a successful reload swaps the configuration", func(t *testing.T) {
+		reloadErr = nil
+
+		assert.NoError(t, a.Reload())
+		assert.Equal(t, c2, a.Client())
+		assert.False(t, a.config().deduplicate)
+		assert.Equal(t, 1.0, testutil.ToFloat64(a.metrics.reloadSuccessful))
+	})
+
+	t.Run("the reload endpoint requires the lifecycle api", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.reload(w, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		require.NoError(t, a.Apply(c2, WithLifecycle(true)))
+
+		w = httptest.NewRecorder()
+		a.reload(w, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,268 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const subscriberBufferSize = 256
+
+// broker polls targets and alerts and publishes the changes between successive
+// snapshots to all subscribers. It only polls while there are subscribers.
+type broker struct {
+	l        *zap.SugaredLogger
+	targets  func(context.Context) (prometheus.Targets, error)
+	alerts   func(context.Context) (prometheus.Alerts, error)
+	interval time.Duration
+	wakeup   chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[chan prometheus.Event]struct{}
+}
+
+func newBroker(l *zap.SugaredLogger, targets func(context.Context) (prometheus.Targets, error),
+	alerts func(context.Context) (prometheus.Alerts, error), interval time.Duration) *broker {
+	return &broker{
+		l:           l,
+		targets:     targets,
+		alerts:      alerts,
+		interval:    interval,
+		wakeup:      make(chan struct{}, 1),
+		subscribers: map[chan prometheus.Event]struct{}{},
+	}
+}
+
+// run polls in the configured interval until the context is canceled.
+func (b *broker) run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var (
+		targets prometheus.Targets
+		alerts  prometheus.Alerts
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.wakeup:
+		}
+
+		if !b.hasSubscribers() {
+			// the next subscriber gets the changes since its subscription only
+			targets, alerts = nil, nil
+			continue
+		}
+
+		events := prometheus.Events{}
+
+		if t, err := b.targets(ctx); err != nil {
+			b.l.Warnw("failed to get targets for events", "err", err)
+		} else {
+			if targets != nil {
+				events = append(events, prometheus.DiffTargets(targets, t)...)
+			}
+
+			targets = t
+		}
+
+		if al, err := b.alerts(ctx); err != nil {
+			b.l.Warnw("failed to get alerts for events", "err", err)
+		} else {
+			if alerts != nil {
+				events = append(events, prometheus.DiffAlerts(alerts, al)...)
+			}
+
+			alerts = al
+		}
+
+		b.publish(events)
+	}
+}
+
+func (b *broker) subscribe() chan prometheus.Event {
+	ch := make(chan prometheus.Event, subscriberBufferSize)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	first := len(b.subscribers) == 1
+	b.mu.Unlock()
+
+	if first {
+		select {
+		case b.wakeup <- struct{}{}:
+		default:
+		}
+	}
+
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan prometheus.Event) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+}
+
+func (b *broker) hasSubscribers() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers) > 0
+}
+
+func (b *broker) publish(events prometheus.Events) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		for i := range events {
+			select {
+			case ch <- events[i]:
+			default:
+				b.l.Warnw("dropping event for slow subscriber", "type", events[i].Type)
+			}
+		}
+	}
+}
+
+// eventStream sends the change events as server-sent events.
+func (a *API) eventStream(w http.ResponseWriter, r *http.Request) {
+	filters, apiErr := eventFilters(r)
+	if apiErr != nil {
+		a.respondError(w, apiErr, nil)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		a.respondError(w, &apiError{errorInternal, errors.New("streaming is not supported")}, nil)
+		return
+	}
+
+	ch := a.events.subscribe()
+	defer a.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(a.events.interval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case e := <-ch:
+			if len(prometheus.Events{e}.Filter(filters...)) == 0 {
+				continue
+			}
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				a.l.Errorw("failed to marshal event", "err", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// eventFilters returns the event filters from the server, job and selector parameters.
+func eventFilters(r *http.Request) ([]prometheus.EventFilterFunc, *apiError) {
+	filters := []prometheus.EventFilterFunc{}
+
+	if v := r.FormValue("server"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'server': %w", err)}
+		}
+
+		filters = append(filters, prometheus.EventByServer(re))
+	}
+
+	if v := r.FormValue("job"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'job': %w", err)}
+		}
+
+		filters = append(filters, prometheus.EventByJob(re))
+	}
+
+	if v := r.FormValue("selector"); v != "" {
+		sel, err := labels.Parse(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'selector': %w", err)}
+		}
+
+		filters = append(filters, prometheus.EventBySelector(sel))
+	}
+
+	return filters, nil
+}
+
+// eventTargets returns the targets for the change events. If a server fails, an error
+// is returned, so that its targets are not reported as removed.
+func (a *API) eventTargets(ctx context.Context) (prometheus.Targets, error) {
+	cfg := a.config()
+
+	ctx, cancel := withTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	targets, warnings, err := cfg.cli.Targets(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(warnings) > 0 {
+		return nil, errors.New(strings.Join(warnings, "; "))
+	}
+
+	return targets, nil
+}
+
+// eventAlerts returns the alerts for the change events. If a server fails, an error
+// is returned, so that its alerts are not reported as resolved.
+func (a *API) eventAlerts(ctx context.Context) (prometheus.Alerts, error) {
+	cfg := a.config()
+
+	ctx, cancel := withTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	alerts, warnings, err := cfg.cli.Alerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(warnings) > 0 {
+		return nil, errors.New(strings.Join(warnings, "; "))
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the metrics of the server.
+type metrics struct {
+	registry             *prom.Registry
+	reloadSuccessful     prom.Gauge
+	reloadSuccessSeconds prom.Gauge
+}
+
+func newMetrics() *metrics {
+	m := metrics{
+		registry: prom.NewRegistry(),
+		reloadSuccessful: prom.NewGauge(prom.GaugeOpts{
+			Name: "promi_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful.",
+		}),
+		reloadSuccessSeconds: prom.NewGauge(prom.GaugeOpts{
+			Name: "promi_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		prom.NewGoCollector(),
+		prom.NewProcessCollector(prom.ProcessCollectorOpts{}),
+		m.reloadSuccessful,
+		m.reloadSuccessSeconds,
+	)
+
+	m.reloadSucceeded()
+
+	return &m
+}
+
+func (m *metrics) reloadSucceeded() {
+	m.reloadSuccessful.Set(1)
+	m.reloadSuccessSeconds.Set(float64(time.Now().Unix()))
+}
+
+func (m *metrics) reloadFailed() {
+	m.reloadSuccessful.Set(0)
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,257 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// requestContext returns a context derived from the request context with the
+// timeout from the optional timeout parameter. The timeout is capped by the
+// configured server timeout.
+func (a *API) requestContext(r *http.Request) (context.Context, context.CancelFunc, *apiError) {
+	timeout := a.config().timeout
+
+	if v := r.FormValue("timeout"); v != "" {
+		d, err := parseDuration(v)
+		if err != nil {
+			return nil, nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'timeout': %w", err)}
+		}
+
+		if d < timeout || timeout <= 0 {
+			timeout = d
+		}
+	}
+
+	ctx, cancel := withTimeout(r.Context(), timeout)
+
+	return ctx, cancel, nil
+}
+
+// withTimeout returns a context with the timeout. A timeout <= 0 means no limit.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// parseDuration parses a duration in seconds (float) or in prometheus duration
+// format (1m, 30s).
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+
+		if ts <= 0 {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It must be positive", s)
+		}
+
+		return time.Duration(ts), nil
+	}
+
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration. It must be positive", s)
+	}
+
+	return time.Duration(d), nil
+}
+
+// timeParam returns the time of the parameter or the default if it is not set.
+func timeParam(r *http.Request, param string, dflt time.Time) (time.Time, *apiError) {
+	v := r.FormValue(param)
+	if v == "" {
+		return dflt, nil
+	}
+
+	t, err := prometheus.ParseTime(v)
+	if err != nil {
+		return time.Time{}, &apiError{errorBadData, fmt.Errorf("invalid parameter '%s': %w", param, err)}
+	}
+
+	return t, nil
+}
+
+// timeRange returns the start and end parameters. They default to the unix epoch and now.
+func timeRange(r *http.Request) (time.Time, time.Time, *apiError) {
+	start, apiErr := timeParam(r, "start", time.Unix(0, 0))
+	if apiErr != nil {
+		return time.Time{}, time.Time{}, apiErr
+	}
+
+	end, apiErr := timeParam(r, "end", time.Now())
+	if apiErr != nil {
+		return time.Time{}, time.Time{}, apiErr
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, &apiError{errorBadData, errors.New("end timestamp must not be before start time")}
+	}
+
+	return start, end, nil
+}
+
+// targetSort returns the target sort keys of the sort parameter and the reverse parameter.
+// The sort parameter is a comma separated list of keys and can be repeated.
+func targetSort(r *http.Request) ([]prometheus.TargetSortKey, bool, *apiError) {
+	if err := r.ParseForm(); err != nil {
+		return nil, false, &apiError{errorBadData, err}
+	}
+
+	keys := []prometheus.TargetSortKey{}
+
+	for _, v := range r.Form["sort"] {
+		for _, s := range strings.Split(v, ",") {
+			k, err := prometheus.ParseTargetSortKey(strings.TrimSpace(s))
+			if err != nil {
+				return nil, false, &apiError{errorBadData, fmt.Errorf("invalid parameter 'sort': %w", err)}
+			}
+
+			keys = append(keys, k)
+		}
+	}
+
+	reverse := false
+
+	if v := r.FormValue("reverse"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, false, &apiError{errorBadData, fmt.Errorf("invalid parameter 'reverse': %w", err)}
+		}
+
+		reverse = b
+	}
+
+	return keys, reverse, nil
+}
+
+// targetFilters returns the target filters from the job, server, scrape_url, last_error
+// (regular expressions), health, selector, stale_for and slower_than (durations) and
+// where (filter expression) parameters.
+func targetFilters(r *http.Request) ([]prometheus.TargetFilterFunc, *apiError) {
+	filters := []prometheus.TargetFilterFunc{}
+
+	regexps := []struct {
+		param  string
+		filter func(*regexp.Regexp) prometheus.TargetFilterFunc
+	}{
+		{"job", prometheus.TargetByJob},
+		{"server", prometheus.TargetByServer},
+		{"scrape_url", prometheus.TargetByScrapeURL},
+		{"last_error", prometheus.TargetByLastError},
+	}
+
+	for _, p := range regexps {
+		v := r.FormValue(p.param)
+		if v == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter '%s': %w", p.param, err)}
+		}
+
+		filters = append(filters, p.filter(re))
+	}
+
+	if v := v1.HealthStatus(r.FormValue("health")); v != "" {
+		if v != v1.HealthGood && v != v1.HealthBad && v != v1.HealthUnknown {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'health': %q is not one of up, down or unknown", v)}
+		}
+
+		filters = append(filters, prometheus.TargetByHealth(v))
+	}
+
+	if v := r.FormValue("selector"); v != "" {
+		sel, err := labels.Parse(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'selector': %w", err)}
+		}
+
+		filters = append(filters, prometheus.TargetBySelector(sel))
+	}
+
+	durations := []struct {
+		param  string
+		filter func(time.Duration) prometheus.TargetFilterFunc
+	}{
+		{"stale_for", prometheus.TargetByStaleFor},
+		{"slower_than", prometheus.TargetBySlowerThan},
+	}
+
+	for _, p := range durations {
+		v := r.FormValue(p.param)
+		if v == "" {
+			continue
+		}
+
+		d, err := parseDuration(v)
+		if err != nil {
+			return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter '%s': %w", p.param, err)}
+		}
+
+		filters = append(filters, p.filter(d))
+	}
+
+	e, apiErr := where(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if e != nil {
+		filters = append(filters, e.TargetFilter())
+	}
+
+	return filters, nil
+}
+
+// alertFilters returns the alert filters from the where (filter expression) parameter.
+func alertFilters(r *http.Request) ([]prometheus.AlertFilterFunc, *apiError) {
+	filters := []prometheus.AlertFilterFunc{}
+
+	e, apiErr := where(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if e != nil {
+		filters = append(filters, e.AlertFilter())
+	}
+
+	return filters, nil
+}
+
+// where returns the parsed filter expression of the where parameter or nil if it is not set.
+func where(r *http.Request) (*prometheus.Expr, *apiError) {
+	v := r.FormValue("where")
+	if v == "" {
+		return nil, nil
+	}
+
+	e, err := prometheus.ParseExpr(v)
+	if err != nil {
+		return nil, &apiError{errorBadData, fmt.Errorf("invalid parameter 'where': %w", err)}
+	}
+
+	return e, nil
+}
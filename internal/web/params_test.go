@@ -0,0 +1,112 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTargetSort(t *testing.T) {
+	t.Run("sort keys must be comma separated or repeated", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/api/v1/targets?sort=duration,label:team&sort=health&reverse=true", nil)
+
+		keys, reverse, err := targetSort(r)
+		require.Nil(t, err)
+		assert.True(t, reverse)
+		assert.Equal(t, []prometheus.TargetSortKey{
+			prometheus.TargetSortByDuration,
+			prometheus.TargetSortByLabel("team"),
+			prometheus.TargetSortByHealth,
+		}, keys)
+	})
+
+	t.Run("invalid parameters must be bad data", func(t *testing.T) {
+		for _, q := range []string{"sort=foo", "reverse=maybe"} {
+			_, _, err := targetSort(httptest.NewRequest("GET", "/api/v1/targets?"+q, nil))
+			require.NotNil(t, err, q)
+			assert.Equal(t, errorBadData, err.typ)
+		}
+	})
+}
+
+func TestTargetFilters(t *testing.T) {
+	now := time.Now()
+
+	targets := prometheus.Targets{
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://a", Health: v1.HealthGood, LastScrape: now, LastScrapeDuration: 0.1}},
+		{ActiveTarget: v1.ActiveTarget{ScrapeURL: "http://b", Health: v1.HealthBad, LastScrape: now.Add(-time.Hour), LastScrapeDuration: 9, LastError: "context deadline exceeded"}},
+	}
+
+	tt := []struct {
+		query    string
+		expected int
+	}{
+		{"", 2},
+		{"stale_for=2m", 1},
+		{"slower_than=5", 1},
+		{"slower_than=10s", 0},
+		{"last_error=deadline", 1},
+		{"health=up&scrape_url=b", 0},
+		{"where=" + url.QueryEscape(`health=up or last_error=~".*deadline.*"`), 2},
+		{"where=" + url.QueryEscape(`not scrape_url="http://a"`), 1},
+	}
+
+	for _, tc := range tt {
+		filters, err := targetFilters(httptest.NewRequest("GET", "/api/v1/targets?"+tc.query, nil))
+		require.Nil(t, err, tc.query)
+		assert.Len(t, targets.Filter(filters...), tc.expected, tc.query)
+	}
+
+	for _, q := range []string{"stale_for=abc", "last_error=(", "health=bad", "selector=a%20in", "where=health%3D"} {
+		_, err := targetFilters(httptest.NewRequest("GET", "/api/v1/targets?"+q, nil))
+		assert.NotNil(t, err, q)
+	}
+}
+
+func TestEventsWithoutTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"success","data":{"alerts":[{"labels":{"alertname":"InstanceDown"},"state":"firing"}]}}`)
+	}))
+	defer s.Close()
+
+	c, err := prometheus.New([]string{s.URL})
+	require.NoError(t, err)
+
+	a, err := New(zap.NewNop().Sugar(), c, WithTimeout(0))
+	require.NoError(t, err)
+
+	alerts, err := a.eventAlerts(context.Background())
+	require.NoError(t, err, "a timeout of 0 means no limit")
+	assert.Len(t, alerts, 1)
+}
+
+func TestEventsOfFailingServer(t *testing.T) {
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"success","data":{"activeTargets":[{"labels":{"job":"node"},"scrapeUrl":"http://n1/metrics","health":"up"}]}}`)
+	}))
+	defer s1.Close()
+
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s2.Close()
+
+	c, err := prometheus.New([]string{s1.URL, s2.URL}, prometheus.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	a, err := New(zap.NewNop().Sugar(), c)
+	require.NoError(t, err)
+
+	_, err = a.eventTargets(context.Background())
+	assert.Error(t, err, "the targets of a failing server must not be reported as removed")
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/postfinance/promi/internal/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// queryData is the data of a query response.
+type queryData struct {
+	ResultType model.ValueType `json:"resultType"`
+	Result     model.Value     `json:"result"`
+}
+
+// query evaluates an instant query on all prometheus servers.
+func (a *API) query(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	q := r.FormValue("query")
+	if q == "" {
+		return apiFuncResult{err: &apiError{errorBadData, errors.New("missing parameter 'query'")}}
+	}
+
+	ts, apiErr := timeParam(r, "time", time.Now())
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	cfg := a.config()
+
+	v, warnings, err := cfg.cli.Query(ctx, q, ts)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	return queryResult(cfg, v, warnings)
+}
+
+// queryRange evaluates a range query on all prometheus servers.
+func (a *API) queryRange(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	q := r.FormValue("query")
+	if q == "" {
+		return apiFuncResult{err: &apiError{errorBadData, errors.New("missing parameter 'query'")}}
+	}
+
+	rng := v1.Range{}
+
+	for _, p := range []struct {
+		param string
+		t     *time.Time
+	}{
+		{"start", &rng.Start},
+		{"end", &rng.End},
+	} {
+		if r.FormValue(p.param) == "" {
+			return apiFuncResult{err: &apiError{errorBadData, fmt.Errorf("missing parameter '%s'", p.param)}}
+		}
+
+		t, apiErr := timeParam(r, p.param, time.Time{})
+		if apiErr != nil {
+			return apiFuncResult{err: apiErr}
+		}
+
+		*p.t = t
+	}
+
+	if rng.End.Before(rng.Start) {
+		return apiFuncResult{err: &apiError{errorBadData, errors.New("end timestamp must not be before start time")}}
+	}
+
+	step, err := parseDuration(r.FormValue("step"))
+	if err != nil {
+		return apiFuncResult{err: &apiError{errorBadData, fmt.Errorf("invalid parameter 'step': %w", err)}}
+	}
+
+	rng.Step = step
+
+	cfg := a.config()
+
+	v, warnings, err := cfg.cli.QueryRange(ctx, q, rng)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	return queryResult(cfg, v, warnings)
+}
+
+// queryResult returns the optionally deduplicated query result.
+func queryResult(cfg config, v model.Value, warnings v1.Warnings) apiFuncResult {
+	if cfg.deduplicate {
+		v = prometheus.DeduplicateValue(v, cfg.replicaLabels...)
+	}
+
+	return apiFuncResult{
+		data: queryData{
+			ResultType: v.Type(),
+			Result:     v,
+		},
+		warnings: warnings,
+	}
+}
+
+// series returns the series matching the match[] parameters of all prometheus servers.
+func (a *API) series(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{err: &apiError{errorBadData, err}}
+	}
+
+	matches := r.Form["match[]"]
+	if len(matches) == 0 {
+		return apiFuncResult{err: &apiError{errorBadData, errors.New("no match[] parameter provided")}}
+	}
+
+	start, end, apiErr := timeRange(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	cfg := a.config()
+
+	series, warnings, err := cfg.cli.Series(ctx, matches, start, end)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	if cfg.deduplicate {
+		series = prometheus.DeduplicateSeries(series, cfg.replicaLabels...)
+	}
+
+	return apiFuncResult{data: series, warnings: warnings}
+}
+
+// labelNames returns the label names of all prometheus servers.
+func (a *API) labelNames(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{err: &apiError{errorBadData, err}}
+	}
+
+	start, end, apiErr := timeRange(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	cfg := a.config()
+
+	names, warnings, err := cfg.cli.LabelNames(ctx, r.Form["match[]"], start, end)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	if cfg.deduplicate {
+		replica := map[string]bool{}
+		for _, l := range cfg.replicaLabels {
+			replica[l] = true
+		}
+
+		filtered := make([]string, 0, len(names))
+
+		for _, n := range names {
+			if !replica[n] {
+				filtered = append(filtered, n)
+			}
+		}
+
+		names = filtered
+	}
+
+	return apiFuncResult{data: names, warnings: warnings}
+}
+
+// labelValues returns the values of a label of all prometheus servers.
+func (a *API) labelValues(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	name := chi.URLParam(r, "name")
+	if !model.LabelName(name).IsValid() {
+		return apiFuncResult{err: &apiError{errorBadData, fmt.Errorf("invalid label name: %q", name)}}
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{err: &apiError{errorBadData, err}}
+	}
+
+	start, end, apiErr := timeRange(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	cfg := a.config()
+
+	if cfg.deduplicate {
+		for _, l := range cfg.replicaLabels {
+			if l == name {
+				return apiFuncResult{data: model.LabelValues{}}
+			}
+		}
+	}
+
+	values, warnings, err := cfg.cli.LabelValues(ctx, name, r.Form["match[]"], start, end)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	return apiFuncResult{data: values, warnings: warnings}
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/postfinance/promi/internal/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestQueryEndpoints(t *testing.T) {
+	replica := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/query", "/api/v1/query_range":
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"node","replica":"%s"},"value":[1628763259,"1"]}]}}`, name)
+			case "/api/v1/labels":
+				fmt.Fprintln(w, `{"status":"success","data":["job","replica"]}`)
+			case "/api/v1/label/job/values":
+				fmt.Fprintln(w, `{"status":"success","data":["node"]}`)
+			case "/api/v1/series":
+				fmt.Fprintf(w, `{"status":"success","data":[{"job":"node","replica":"%s"}]}`, name)
+			}
+		}))
+	}
+
+	s1 := replica("a")
+	defer s1.Close()
+
+	s2 := replica("b")
+	defer s2.Close()
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s3.Close()
+
+	c, err := prometheus.New([]string{s1.URL, s2.URL, s3.URL}, prometheus.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	a, err := New(zap.NewNop().Sugar(), c)
+	require.NoError(t, err)
+	require.NoError(t, a.routes())
+
+	get := func(t *testing.T, method, url string) (int, response) {
+		rec := httptest.NewRecorder()
+		a.router.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+
+		resp := response{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+		return rec.Code, resp
+	}
+
+	tt := []struct {
+		name     string
+		method   string
+		url      string
+		code     int
+		expected string
+		dedup    string
+	}{
+		{"query", http.MethodGet, "/api/v1/query?query=up", http.StatusOK, `"resultType":"vector"`, `"result":[{"metric":{"job":"node","promi_scrape_src":"127.0.0.1:`},
+		{"query with POST", http.MethodPost, "/api/v1/query?query=up", http.StatusOK, `"resultType":"vector"`, `"job":"node"`},
+		{"query range", http.MethodGet, "/api/v1/query_range?query=up&start=1628763000&end=2021-08-12T10:14:19Z&step=15s", http.StatusOK, `"resultType":"vector"`, `"job":"node"`},
+		{"series", http.MethodGet, "/api/v1/series?match[]=up", http.StatusOK, `"replica":"b"`, `"job":"node"`},
+		{"labels", http.MethodGet, "/api/v1/labels", http.StatusOK, `["job","promi_scrape_src","replica"]`, `["job","promi_scrape_src"]`},
+		{"label values", http.MethodGet, "/api/v1/label/job/values", http.StatusOK, `["node"]`, `["node"]`},
+		{"missing query", http.MethodGet, "/api/v1/query", http.StatusBadRequest, "", ""},
+		{"invalid time", http.MethodGet, "/api/v1/query?query=up&time=yesterday", http.StatusBadRequest, "", ""},
+		{"missing step", http.MethodGet, "/api/v1/query_range?query=up&start=1&end=2", http.StatusBadRequest, "", ""},
+		{"end before start", http.MethodGet, "/api/v1/query_range?query=up&start=2&end=1&step=1", http.StatusBadRequest, "", ""},
+		{"missing match", http.MethodGet, "/api/v1/series", http.StatusBadRequest, "", ""},
+		{"invalid label name", http.MethodGet, "/api/v1/label/1job/values", http.StatusBadRequest, "", ""},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, a.Apply(c, WithDeduplicate(false)))
+
+			code, resp := get(t, tc.method, tc.url)
+			require.Equal(t, tc.code, code)
+
+			if tc.code != http.StatusOK {
+				assert.Equal(t, errorBadData, resp.ErrorType)
+				return
+			}
+
+			// the failing server is returned as warning
+			require.Len(t, resp.Warnings, 1)
+
+			b, err := json.Marshal(resp.Data)
+			require.NoError(t, err)
+			assert.Contains(t, string(b), tc.expected)
+
+			require.NoError(t, a.Apply(c, WithDeduplicate(true, "replica")))
+
+			_, resp = get(t, tc.method, tc.url)
+
+			b, err = json.Marshal(resp.Data)
+			require.NoError(t, err)
+			assert.Contains(t, string(b), tc.dedup)
+			assert.NotContains(t, string(b), `"replica":`)
+		})
+	}
+}
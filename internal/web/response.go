@@ -0,0 +1,146 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+type status string
+
+const (
+	statusSuccess status = "success"
+	statusError   status = "error"
+)
+
+type errorType string
+
+const (
+	errorTimeout     errorType = "timeout"
+	errorBadData     errorType = "bad_data"
+	errorInternal    errorType = "internal"
+	errorUnavailable errorType = "unavailable"
+)
+
+// apiError is an error with a prometheus API error type.
+type apiError struct {
+	typ errorType
+	err error
+}
+
+func (e *apiError) Error() string {
+	return string(e.typ) + ": " + e.err.Error()
+}
+
+// code returns the http status code for the error type.
+func (e *apiError) code() int {
+	switch e.typ {
+	case errorBadData:
+		return http.StatusBadRequest
+	case errorTimeout, errorUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// response is the prometheus API response envelope.
+type response struct {
+	Status    status      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType errorType   `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Warnings  []string    `json:"warnings,omitempty"`
+}
+
+// apiFuncResult is the result of an apiFunc. If err is not nil, an error
+// response is sent, otherwise data is sent with status success.
+type apiFuncResult struct {
+	data     interface{}
+	err      *apiError
+	warnings []string
+}
+
+type apiFunc func(r *http.Request) apiFuncResult
+
+// wrap converts an apiFunc to a http.HandlerFunc that writes a prometheus API
+// response envelope.
+func (a *API) wrap(f apiFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := f(r)
+		if result.err != nil {
+			a.respondError(w, result.err, result.data)
+			return
+		}
+
+		a.respond(w, result.data, result.warnings)
+	}
+}
+
+func (a *API) respond(w http.ResponseWriter, data interface{}, warnings []string) {
+	a.write(w, http.StatusOK, response{
+		Status:   statusSuccess,
+		Data:     data,
+		Warnings: warnings,
+	})
+}
+
+func (a *API) respondError(w http.ResponseWriter, apiErr *apiError, data interface{}) {
+	a.l.Debugw("api error", "type", apiErr.typ, "err", apiErr.err)
+
+	a.write(w, apiErr.code(), response{
+		Status:    statusError,
+		ErrorType: apiErr.typ,
+		Error:     apiErr.err.Error(),
+		Data:      data,
+	})
+}
+
+func (a *API) write(w http.ResponseWriter, code int, resp response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		a.l.Errorw("failed to marshal response", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if _, err := w.Write(b); err != nil {
+		a.l.Errorw("failed to write response", "err", err)
+	}
+}
+
+// upstreamError maps errors from prometheus servers to an apiError.
+func upstreamError(err error) *apiError {
+	var (
+		netErr  net.Error
+		promErr *v1.Error
+	)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &apiError{errorTimeout, err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &apiError{errorTimeout, err}
+	case errors.As(err, &netErr):
+		return &apiError{errorUnavailable, err}
+	case errors.As(err, &promErr):
+		switch promErr.Type {
+		case v1.ErrBadData:
+			return &apiError{errorBadData, err}
+		case v1.ErrTimeout:
+			return &apiError{errorTimeout, err}
+		case v1.ErrServer, v1.ErrBadResponse:
+			return &apiError{errorUnavailable, err}
+		}
+	}
+
+	return &apiError{errorInternal, err}
+}
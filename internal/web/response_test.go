@@ -0,0 +1,76 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWrap(t *testing.T) {
+	a := &API{l: zap.NewNop().Sugar()}
+
+	t.Run("success response", func(t *testing.T) {
+		h := a.wrap(func(r *http.Request) apiFuncResult {
+			return apiFuncResult{data: "data", warnings: []string{"warning"}}
+		})
+
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		resp := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, map[string]interface{}{
+			"status":   "success",
+			"data":     "data",
+			"warnings": []interface{}{"warning"},
+		}, resp)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		h := a.wrap(func(r *http.Request) apiFuncResult {
+			return apiFuncResult{err: &apiError{errorBadData, errors.New("invalid parameter")}}
+		})
+
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		resp := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, map[string]interface{}{
+			"status":    "error",
+			"errorType": "bad_data",
+			"error":     "invalid parameter",
+		}, resp)
+	})
+}
+
+func TestUpstreamError(t *testing.T) {
+	var tests = []struct {
+		err      error
+		expected errorType
+	}{
+		{context.DeadlineExceeded, errorTimeout},
+		{&v1.Error{Type: v1.ErrServer, Msg: "server error: 502"}, errorUnavailable},
+		{&v1.Error{Type: v1.ErrTimeout, Msg: "query timed out"}, errorTimeout},
+		{&v1.Error{Type: v1.ErrBadData, Msg: "parse error"}, errorBadData},
+		{errors.New("invalid label name"), errorInternal},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, upstreamError(tc.err).typ)
+		})
+	}
+}
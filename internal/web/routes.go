@@ -1,8 +1,7 @@
 package web
 
 import (
-	"context"
-	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"path"
@@ -16,8 +15,24 @@ func (a *API) routes() error {
 	a.router.Get("/classic/graph", redirectToTargets)
 	a.router.Get("/graph", redirectToTargets)
 
-	a.router.Get(path.Join(a.urlPathPrefix, "/api/v1/targets"), a.targets)
+	a.router.Get(path.Join(a.urlPathPrefix, "/api/v1/targets"), a.wrap(a.targets))
+	a.router.Get(path.Join(a.urlPathPrefix, "/api/v1/alerts"), a.wrap(a.alerts))
+	a.router.Get(path.Join(a.urlPathPrefix, "/api/promi/v1/events"), a.eventStream)
+
+	// the query endpoints accept GET and POST like prometheus
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		a.router.Method(method, path.Join(a.urlPathPrefix, "/api/v1/query"), a.wrap(a.query))
+		a.router.Method(method, path.Join(a.urlPathPrefix, "/api/v1/query_range"), a.wrap(a.queryRange))
+		a.router.Method(method, path.Join(a.urlPathPrefix, "/api/v1/series"), a.wrap(a.series))
+		a.router.Method(method, path.Join(a.urlPathPrefix, "/api/v1/labels"), a.wrap(a.labelNames))
+	}
+
+	a.router.Get(path.Join(a.urlPathPrefix, "/api/v1/label/{name}/values"), a.wrap(a.labelValues))
+
 	a.router.Get(path.Join(a.urlPathPrefix, "/-/ready"), a.ready)
+	a.router.Post(path.Join(a.urlPathPrefix, "/-/reload"), a.reload)
+	a.router.Put(path.Join(a.urlPathPrefix, "/-/reload"), a.reload)
+	a.router.Method(http.MethodGet, path.Join(a.urlPathPrefix, "/metrics"), a.metrics.handler())
 
 	fileserver.FileServer(a.router, "/targets", a.reactApp)
 
@@ -28,60 +43,101 @@ func redirectToTargets(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/targets", http.StatusFound)
 }
 
-func (a *API) targets(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+func (a *API) targets(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
 	defer cancel()
 
-	targets, err := a.cli.Targets(ctx, true)
+	keys, reverse, apiErr := targetSort(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	filters, apiErr := targetFilters(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+
+	cfg := a.config()
+
+	targets, warnings, err := cfg.cli.Targets(ctx, true)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return apiFuncResult{err: upstreamError(err)}
 	}
 
-	if a.deduplicate {
-		targets = targets.Deduplicate()
+	targets = targets.Filter(filters...)
+
+	if cfg.deduplicate {
+		targets = targets.Deduplicate(cfg.dedupOpts...)
 	}
-	targets.Sort()
-
-	enc := json.NewEncoder(w)
-	resp := struct {
-		Status    string      `json:"status"`
-		Data      interface{} `json:"data,omitempty"`
-		ErrorType string      `json:"errorType,omitempty"`
-		Error     string      `json:"error,omitempty"`
-		Warnings  []string    `json:"warnings,omitempty"`
-	}{
-		Status: statusSuccess,
-		Data: struct {
+
+	targets.SortBy(reverse, keys...)
+
+	return apiFuncResult{
+		data: struct {
 			ActiveTargets  []*v1.ActiveTarget  `json:"activeTargets"`
 			DroppedTargets []*v1.DroppedTarget `json:"droppedTargets"`
 		}{
 			ActiveTargets:  targets.Active(),
 			DroppedTargets: []*v1.DroppedTarget{},
 		},
+		warnings: warnings,
 	}
-	if err := enc.Encode(resp); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (a *API) alerts(r *http.Request) apiFuncResult {
+	ctx, cancel, apiErr := a.requestContext(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
+	}
+	defer cancel()
+
+	filters, apiErr := alertFilters(r)
+	if apiErr != nil {
+		return apiFuncResult{err: apiErr}
 	}
 
+	cfg := a.config()
+
+	alerts, warnings, err := cfg.cli.Alerts(ctx)
+	if err != nil {
+		return apiFuncResult{err: upstreamError(err)}
+	}
+
+	alerts = alerts.Filter(filters...)
+
+	if cfg.deduplicate {
+		alerts = alerts.Deduplicate(cfg.replicaLabels...)
+	}
+
+	alerts.Sort()
+
+	active := make([]v1.Alert, 0, len(alerts))
+	for i := range alerts {
+		active = append(active, alerts[i].Alert)
+	}
+
+	return apiFuncResult{
+		data: v1.AlertsResult{
+			Alerts: active,
+		},
+		warnings: warnings,
+	}
 }
 
 func (a *API) ready(w http.ResponseWriter, r *http.Request) {
 	_, _ = io.WriteString(w, "Prometheus is Ready.")
 }
 
-const (
-	statusSuccess = "success"
-)
+func (a *API) reload(w http.ResponseWriter, r *http.Request) {
+	if !a.config().lifecycle {
+		http.Error(w, "lifecycle api is not enabled", http.StatusForbidden)
+		return
+	}
 
-/*
-const (
-	errorNone        errorType = ""
-	errorTimeout     errorType = "timeout"
-	errorCanceled    errorType = "canceled"
-	errorExec        errorType = "execution"
-	errorBadData     errorType = "bad_data"
-	errorInternal    errorType = "internal"
-	errorUnavailable errorType = "unavailable"
-	errorNotFound    errorType = "not_found"
-)
-*/
+	if err := a.Reload(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+	}
+}